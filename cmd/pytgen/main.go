@@ -9,7 +9,11 @@ import (
 
 func main() {
 	// Create CLI app
-	app := cli.NewApp()
+	app, err := cli.NewApp(os.Args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		os.Exit(1)
+	}
 
 	// Run the CLI app
 	if err := app.Run(os.Args); err != nil {
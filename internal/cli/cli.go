@@ -1,62 +1,221 @@
 package cli
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
 	"github.com/ShmuelRob/templates-cli/internal/templates"
+	"github.com/ShmuelRob/templates-cli/internal/utils"
 	"github.com/urfave/cli/v2"
 )
 
-// NewApp creates a new CLI application
-func NewApp() *cli.App {
+// localTemplatesFlag opts in to the templates directory on disk
+const localTemplatesFlag = "local-templates"
+
+// localTemplatesEnv opts in to the templates directory on disk like
+// localTemplatesFlag
+const localTemplatesEnv = "PYTGEN_LOCAL_TEMPLATES"
+
+// NewApp creates a new CLI application with one command per discovered
+// template. args are the command line arguments, read for the local
+// templates opt-in before the commands are built.
+func NewApp(args []string) (*cli.App, error) {
+	// Fetched templates override built-in ones; local templates, when asked
+	// for, override both
+	dirs := []string{utils.GetCacheDir()}
+	if useLocalTemplates(args) {
+		dirs = append(dirs, utils.GetTemplatesDir())
+	}
+	reg, err := templates.Discover(dirs...)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range reg.Warnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
 	app := &cli.App{
 		Name:    "pytgen",
 		Usage:   "Generate Python project templates",
 		Version: "0.1.0",
-		Commands: []*cli.Command{
-			{
-				Name:  "etl",
-				Usage: "Generate a Python ETL project template",
-				Flags: []cli.Flag{
-					&cli.StringFlag{
-						Name:    "name",
-						Aliases: []string{"n"},
-						Usage:   "Project name",
-						Value:   "python-etl-project",
-					},
-					&cli.StringFlag{
-						Name:    "extract",
-						Aliases: []string{"e"},
-						Usage:   "Extract method (file, api, database)",
-						Value:   "file",
-					},
-					&cli.StringFlag{
-						Name:    "transform",
-						Aliases: []string{"t"},
-						Usage:   "Transform method (basic, advanced)",
-						Value:   "basic",
-					},
-					&cli.StringFlag{
-						Name:    "load",
-						Aliases: []string{"l"},
-						Usage:   "Load destination (file, database, api)",
-						Value:   "file",
-					},
-					&cli.BoolFlag{
-						Name:  "venv",
-						Usage: "Initialize virtual environment",
-						Value: false,
-					},
-				},
-				Action: templates.GenerateETLTemplate,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:    localTemplatesFlag,
+				Usage:   "Read templates from the templates directory on disk, overriding the built-in and fetched ones",
+				EnvVars: []string{localTemplatesEnv},
 			},
-			// Add a new interactive command
-			{
-				Name:    "interactive",
-				Aliases: []string{"i"},
-				Usage:   "Launch interactive project generator",
-				Action:  templates.InteractiveGenerator,
+		},
+	}
+
+	for _, t := range reg.Templates() {
+		app.Commands = append(app.Commands, templateCommand(t))
+	}
+
+	// Add a new interactive command
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:    "interactive",
+		Aliases: []string{"i"},
+		Usage:   "Launch interactive project generator",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:  "save-answers",
+				Usage: "Write the answers to a YAML or JSON file for use with --answers",
+			},
+		}, generationFlags()...),
+		Action: templates.InteractiveGenerator(reg),
+	})
+
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:   "list",
+		Usage:  "List the available templates",
+		Action: templates.ListTemplates(reg),
+	})
+
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:      "describe",
+		Usage:     "Show the options of a template and the dependencies they add",
+		ArgsUsage: "TEMPLATE",
+		Action:    templates.DescribeTemplate(reg),
+	})
+
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:      "add",
+		Usage:     "Fetch a template from a git repository or archive",
+		ArgsUsage: "git+URL[//DIR][@REF] | ARCHIVE[//DIR]",
+		Description: "Fetches the template into " + utils.GetCacheDir() + " and adds a command for it.\n" +
+			"Adding a template again fetches it anew, e.g. to pick up a new ref.",
+		Action: templates.AddTemplate(reg, utils.GetCacheDir()),
+	})
+
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:  "update",
+		Usage: "Re-apply the current version of its template to a generated project",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "dir",
+				Aliases: []string{"d"},
+				Usage:   "Project directory",
+				Value:   ".",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Print the changes that would be made without writing anything",
 			},
 		},
+		Action: templates.UpdateProject(reg),
+	})
+
+	return app, nil
+}
+
+// useLocalTemplates reports whether the global flags in args or the
+// environment opt in to the templates directory on disk
+func useLocalTemplates(args []string) bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(localTemplatesEnv))
+	for i, arg := range args {
+		if i == 0 {
+			continue
+		}
+		// Global flags come before the command
+		if !strings.HasPrefix(arg, "-") {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != localTemplatesFlag {
+			continue
+		}
+		enabled = true
+		if hasValue {
+			enabled, _ = strconv.ParseBool(value)
+		}
+	}
+	return enabled
+}
+
+// templateCommand builds the command generating template t, with a flag for
+// each of its manifest variables and of the config fields that declare one
+func templateCommand(t *templates.Template) *cli.Command {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:    "name",
+			Aliases: []string{"n"},
+			Usage:   "Project name",
+			Value:   t.DefaultName,
+		},
 	}
 
-	return app
+	for _, v := range t.Variables {
+		usage := v.Description
+		if len(v.Options) > 0 {
+			usage = fmt.Sprintf("%s (%s)", usage, strings.Join(v.Values(), ", "))
+		}
+
+		flag := &cli.StringFlag{
+			Name:  v.Name,
+			Usage: usage,
+			Value: v.Default,
+		}
+		if v.Alias != "" {
+			flag.Aliases = []string{v.Alias}
+		}
+		flags = append(flags, flag)
+	}
+
+	// Settings of the chosen components that can be given on the command line
+	for _, f := range t.ConfigFlags() {
+		usage := f.Description
+		if len(f.Options) > 0 {
+			usage = fmt.Sprintf("%s (%s)", usage, strings.Join(f.Options, ", "))
+		}
+		flags = append(flags, &cli.StringFlag{
+			Name:  f.Flag,
+			Usage: strings.TrimSpace(usage),
+		})
+	}
+
+	flags = append(flags,
+		&cli.BoolFlag{
+			Name:  "venv",
+			Usage: "Initialize virtual environment",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:    "answers",
+			Aliases: []string{"a"},
+			Usage:   "Read answers from a YAML or JSON file (flags set explicitly take precedence)",
+		},
+	)
+	flags = append(flags, generationFlags()...)
+
+	usage := t.Usage
+	if usage == "" {
+		usage = fmt.Sprintf("Generate a %s project", t.ProjectType)
+	}
+
+	return &cli.Command{
+		Name:  t.Command,
+		Usage: usage,
+		Flags: flags,
+		Action: func(c *cli.Context) error {
+			return templates.Generate(c, t)
+		},
+	}
+}
+
+// generationFlags returns the flags controlling how generated files are
+// written, shared by the template commands and the interactive wizard
+func generationFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Print the files that would be generated without writing anything",
+		},
+		&cli.StringFlag{
+			Name:  "on-conflict",
+			Usage: fmt.Sprintf("What to do with existing files that differ (%s)", strings.Join(templates.ConflictPolicies, ", ")),
+			Value: string(templates.ConflictFail),
+		},
+	}
 }
@@ -0,0 +1,65 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// isJSONFile reports whether an answers file should use JSON rather than YAML
+func isJSONFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// readAnswers decodes a YAML or JSON answers file into v. Fields missing
+// from the file keep their current value.
+func readAnswers(path string, v interface{}) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read answers file: %w", err)
+	}
+
+	if isJSONFile(path) {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(v)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		err = decoder.Decode(v)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse answers file %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// writeAnswers saves v as a YAML or JSON answers file, chosen by extension
+func writeAnswers(path string, v interface{}) error {
+	var content []byte
+	var err error
+	if isJSONFile(path) {
+		content, err = json.MarshalIndent(v, "", "  ")
+		content = append(content, '\n')
+	} else {
+		content, err = yaml.Marshal(v)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode answers: %w", err)
+	}
+
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write answers file: %w", err)
+	}
+
+	fmt.Printf("Answers saved to %s\n", path)
+	return nil
+}
@@ -0,0 +1,537 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Component is a catalog entry: one allowed value of a variable, such as the
+// "api" extract method, with everything choosing it implies. In the manifest
+// it is either the bare name or a mapping.
+type Component struct {
+	Name         string              `yaml:"name"`
+	Description  string              `yaml:"description"`
+	Dependencies []string            `yaml:"dependencies"` // Python packages it adds
+	Replaces     map[string][]string `yaml:"replaces"`     // packages it swaps for others, whoever adds them
+	Requires     []ConfigField       `yaml:"requires"`     // answer fields it needs
+	Files        []FileSpec          `yaml:"files"`        // template fragments it renders
+}
+
+// ConfigField is an answer a component needs, named by its dotted path in
+// the answers file, e.g. source.url. In the manifest it is either the bare
+// path or a mapping.
+//
+// When, Default, Prompt and Help are templates executed with the answers
+// given so far, so that they may depend on the fields before them. When is
+// a pipeline like the condition of a FileSpec: the field only applies, and
+// is only asked for, defaulted and required, if it evaluates to a non-empty
+// value.
+type ConfigField struct {
+	Field        string              `yaml:"field"`
+	Options      []string            `yaml:"options"`      // allowed values, if restricted
+	Optional     bool                `yaml:"optional"`     // may be left empty
+	Dependencies map[string][]string `yaml:"dependencies"` // Python packages added by each value
+	Flag         string              `yaml:"flag"`         // command flag setting the field, if any
+	Description  string              `yaml:"description"`  // usage of the flag
+	File         bool                `yaml:"file"`         // the flag names a YAML or JSON file holding the value
+	List         bool                `yaml:"list"`         // a comma-separated list of values
+	When         string              `yaml:"when"`         // condition the field applies on
+	Default      string              `yaml:"default"`      // value set when the field is left empty
+	Prompt       string              `yaml:"prompt"`       // wizard question, the description if empty
+	Help         string              `yaml:"help"`         // wizard help text
+	Choices      map[string]string   `yaml:"choices"`      // wizard descriptions of the options
+	Validate     string              `yaml:"validate"`     // check the wizard runs on an answer, out of fieldValidators
+}
+
+// UnmarshalYAML accepts a component given as its bare name
+func (c *Component) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		c.Name = node.Value
+		return nil
+	}
+
+	type plain Component
+	return node.Decode((*plain)(c))
+}
+
+// UnmarshalYAML accepts a config field given as its bare path
+func (f *ConfigField) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		f.Field = node.Value
+		return nil
+	}
+
+	type plain ConfigField
+	return node.Decode((*plain)(f))
+}
+
+// validateComponent checks a catalog entry of variable v
+func validateComponent(dir fs.FS, v Variable, c Component) error {
+	if c.Name == "" {
+		return fmt.Errorf("%s: every option of variable %s needs a name", ManifestFile, v.Name)
+	}
+	for _, f := range c.Requires {
+		if f.Field == "" {
+			return fmt.Errorf("%s: option %s of variable %s requires a field without a name", ManifestFile, c.Name, v.Name)
+		}
+		if contains(reservedFlags, f.Flag) {
+			return fmt.Errorf("%s: field %s of option %s uses the reserved flag %s", ManifestFile, f.Field, c.Name, f.Flag)
+		}
+		if err := validateFieldTemplates(f); err != nil {
+			return fmt.Errorf("%s: option %s of variable %s: %v", ManifestFile, c.Name, v.Name, err)
+		}
+	}
+	return validateFileSpecs(dir, c.Files)
+}
+
+// validateConfigField checks a field every project requires
+func validateConfigField(f ConfigField) error {
+	if f.Field == "" {
+		return fmt.Errorf("every required field needs a name")
+	}
+	if contains(reservedFlags, f.Flag) {
+		return fmt.Errorf("field %s uses the reserved flag %s", f.Field, f.Flag)
+	}
+	return validateFieldTemplates(f)
+}
+
+// validateFieldTemplates checks the templates and the validator of a field
+func validateFieldTemplates(f ConfigField) error {
+	if _, err := parseCondition(f.When); err != nil {
+		return fmt.Errorf("invalid condition for %s: %v", f.Field, err)
+	}
+	for what, text := range map[string]string{"default": f.Default, "prompt": f.Prompt, "help": f.Help} {
+		if _, err := template.New(what).Parse(text); err != nil {
+			return fmt.Errorf("invalid %s for %s: %v", what, f.Field, err)
+		}
+	}
+	if _, ok := fieldValidators[f.Validate]; f.Validate != "" && !ok {
+		return fmt.Errorf("unknown validator %s for %s", f.Validate, f.Field)
+	}
+	return nil
+}
+
+// fieldValidators are the checks a field may name in its validate setting.
+// A check is given the text of an answer, or the path of a file field.
+var fieldValidators = map[string]func(string) error{
+	"hostname":         validateHostname,
+	"port":             validatePort,
+	"rate_limit":       validateRateLimit,
+	"page_size":        validatePageSize,
+	"max_retries":      validateMaxRetries,
+	"backoff":          validateBackoff,
+	"schedule":         validateSchedule,
+	"task_retries":     validateTaskRetries,
+	"task_retry_delay": validateTaskRetryDelay,
+	"identifier": func(name string) error {
+		return validateName("name", name)
+	},
+	"json_object": func(value string) error {
+		return validateJSONObject("JSON object", value)
+	},
+	"sample": func(path string) error {
+		_, err := InferSchema(path)
+		return err
+	},
+	"transform_spec": func(path string) error {
+		_, err := readTransformSpec(path)
+		return err
+	},
+}
+
+// applies reports whether the condition of f holds for answers
+func (f ConfigField) applies(answers interface{}) (bool, error) {
+	cond, err := parseCondition(f.When)
+	if err != nil {
+		return false, fmt.Errorf("invalid condition for %s: %w", f.Field, err)
+	}
+	if cond == nil {
+		return true, nil
+	}
+	var out strings.Builder
+	if err := cond.Execute(&out, answers); err != nil {
+		return false, fmt.Errorf("failed to evaluate the condition for %s: %w", f.Field, err)
+	}
+	return out.Len() > 0, nil
+}
+
+// expand executes text, the named template of f such as its default, with
+// answers
+func (f ConfigField) expand(what, text string, answers interface{}) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := template.New(what).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s for %s: %w", what, f.Field, err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, answers); err != nil {
+		return "", fmt.Errorf("failed to resolve the %s of %s: %w", what, f.Field, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// value returns the answer set for the text given for f
+func (f ConfigField) value(text string) interface{} {
+	if f.List {
+		return splitColumns(text)
+	}
+	return text
+}
+
+// reservedFlags are the flags every template command has
+var reservedFlags = []string{"name", "venv", "answers", "dry-run", "on-conflict", "help"}
+
+// Values returns the allowed values of v, or nil if any value is allowed
+func (v Variable) Values() []string {
+	var values []string
+	for _, c := range v.Options {
+		values = append(values, c.Name)
+	}
+	return values
+}
+
+// Component returns the catalog entry of v with the given name
+func (v Variable) Component(name string) (Component, bool) {
+	for _, c := range v.Options {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Component{}, false
+}
+
+// Variable returns the variable with the given name
+func (m Manifest) Variable(name string) (Variable, bool) {
+	for _, v := range m.Variables {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Variable{}, false
+}
+
+// Components returns the catalog entries chosen by vars
+func (m Manifest) Components(vars map[string]string) []Component {
+	var chosen []Component
+	for _, v := range m.Variables {
+		if c, ok := v.Component(vars[v.Name]); ok {
+			chosen = append(chosen, c)
+		}
+	}
+	return chosen
+}
+
+// DependenciesFor returns the dependencies of every project followed by
+// those added by the chosen components and by the values of the fields they
+// require, without duplicates. Packages replaced by a chosen component are
+// swapped for their replacements. answers may be nil.
+func (m Manifest) DependenciesFor(vars map[string]string, answers interface{}) []string {
+	replaces := map[string][]string{}
+	for _, c := range m.Components(vars) {
+		for dep, replacements := range c.Replaces {
+			replaces[dep] = append(replaces[dep], replacements...)
+		}
+	}
+
+	var dependencies []string
+	add := func(deps []string) {
+		for _, dep := range deps {
+			replacements, ok := replaces[dep]
+			if !ok {
+				replacements = []string{dep}
+			}
+			for _, dep := range replacements {
+				if !contains(dependencies, dep) {
+					dependencies = append(dependencies, dep)
+				}
+			}
+		}
+	}
+
+	// Answers have been validated by now, so they can be inspected
+	fields, _ := answerFields(answers)
+
+	add(m.Dependencies)
+	for _, f := range m.Requires {
+		add(f.Dependencies[answerField(fields, f.Field)])
+	}
+	for _, c := range m.Components(vars) {
+		add(c.Dependencies)
+		for _, f := range c.Requires {
+			add(f.Dependencies[answerField(fields, f.Field)])
+		}
+	}
+	return dependencies
+}
+
+// FilesFor returns the files of the manifest followed by the template
+// fragments of the chosen components
+func (m Manifest) FilesFor(vars map[string]string) []FileSpec {
+	files := append([]FileSpec(nil), m.Files...)
+	for _, c := range m.Components(vars) {
+		files = append(files, c.Files...)
+	}
+	return files
+}
+
+// ConfigFlags returns the fields required by every project or by any
+// component that can be set with a command flag, one per flag
+func (m Manifest) ConfigFlags() []ConfigField {
+	var flags []ConfigField
+	seen := map[string]bool{}
+	for _, f := range m.Requires {
+		if f.Flag != "" && !seen[f.Flag] {
+			seen[f.Flag] = true
+			flags = append(flags, f)
+		}
+	}
+	for _, v := range m.Variables {
+		for _, c := range v.Options {
+			for _, f := range c.Requires {
+				if f.Flag != "" && !seen[f.Flag] {
+					seen[f.Flag] = true
+					flags = append(flags, f)
+				}
+			}
+		}
+	}
+	return flags
+}
+
+// RequiresField reports whether every project or a chosen component
+// requires field
+func (m Manifest) RequiresField(vars map[string]string, field string) bool {
+	for _, f := range m.Requires {
+		if f.Field == field {
+			return true
+		}
+	}
+	for _, c := range m.Components(vars) {
+		for _, f := range c.Requires {
+			if f.Field == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RequiredFields returns the fields required by every project followed by
+// those required by the components chosen by vars, each field once per
+// condition
+func (m Manifest) RequiredFields(vars map[string]string) []ConfigField {
+	var fields []ConfigField
+	seen := map[[2]string]bool{}
+	add := func(requires []ConfigField) {
+		for _, f := range requires {
+			if key := [2]string{f.Field, f.When}; !seen[key] {
+				seen[key] = true
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	add(m.Requires)
+	for _, c := range m.Components(vars) {
+		add(c.Requires)
+	}
+	return fields
+}
+
+// checkRequirements checks the answer fields required by the components
+// chosen by vars whose condition holds. Fields are looked up by their
+// answers file keys.
+func checkRequirements(m Manifest, vars map[string]string, answers interface{}) error {
+	fields, err := answerFields(answers)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range m.Requires {
+		ok, err := f.applies(answers)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		value := answerField(fields, f.Field)
+		if value == "" && !f.Optional {
+			return fmt.Errorf("%s is required", f.Field)
+		}
+		if value != "" && len(f.Options) > 0 && !contains(f.Options, value) {
+			return fmt.Errorf("invalid %s: %s. Valid options are: %s", f.Field, value, strings.Join(f.Options, ", "))
+		}
+	}
+
+	for _, v := range m.Variables {
+		c, ok := v.Component(vars[v.Name])
+		if !ok {
+			continue
+		}
+
+		for _, f := range c.Requires {
+			ok, err := f.applies(answers)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			value := answerField(fields, f.Field)
+			if value == "" {
+				if f.Optional {
+					continue
+				}
+				return fmt.Errorf("%s %s requires %s", v.Name, c.Name, f.Field)
+			}
+			if len(f.Options) > 0 && !contains(f.Options, value) {
+				return fmt.Errorf("invalid %s for %s %s: %s. Valid options are: %s",
+					f.Field, v.Name, c.Name, value, strings.Join(f.Options, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// applyConfigDefaults sets the fields required by the manifest and by the
+// components chosen by vars that apply to answers and are empty to their
+// defaults. Fields are defaulted in order, so a default may depend on the
+// fields before it. answers must be a pointer.
+func applyConfigDefaults(m Manifest, vars map[string]string, answers interface{}) error {
+	fields, err := answerFields(answers)
+	if err != nil {
+		return err
+	}
+
+	// Defaults are set together, unless a template may read those before it
+	defaulted := map[string]bool{}
+	pending := map[string]interface{}{}
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		err := setAnswerFields(answers, pending)
+		pending = map[string]interface{}{}
+		return err
+	}
+
+	for _, f := range m.RequiredFields(vars) {
+		if f.Default == "" || defaulted[f.Field] || answerField(fields, f.Field) != "" {
+			continue
+		}
+		if f.When != "" || strings.Contains(f.Default, "{{") {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		ok, err := f.applies(answers)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		def, err := f.expand("default", f.Default, answers)
+		if err != nil {
+			return err
+		}
+		if def != "" {
+			defaulted[f.Field] = true
+			pending[f.Field] = f.value(def)
+		}
+	}
+	return flush()
+}
+
+// answerFields returns answers keyed the way they are in an answers file
+func answerFields(answers interface{}) (map[string]interface{}, error) {
+	encoded, err := yaml.Marshal(answers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect answers: %w", err)
+	}
+	var fields map[string]interface{}
+	if err := yaml.Unmarshal(encoded, &fields); err != nil {
+		return nil, fmt.Errorf("failed to inspect answers: %w", err)
+	}
+	return fields, nil
+}
+
+// answerField returns the answer at a dotted path as a string, or an empty
+// string if it is missing or empty
+func answerField(fields map[string]interface{}, path string) string {
+	var value interface{} = fields
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = m[key]
+	}
+
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case map[string]interface{}:
+		// A group of settings counts as set if any of them is
+		if len(value) == 0 {
+			return ""
+		}
+		return "set"
+	case []interface{}:
+		var items []string
+		for _, item := range value {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ", ")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// setAnswerFields sets answers at dotted paths, given as they would be
+// written in an answers file. answers must be a pointer.
+func setAnswerFields(answers interface{}, values map[string]interface{}) error {
+	fields, err := answerFields(answers)
+	if err != nil {
+		return err
+	}
+	if fields == nil {
+		fields = map[string]interface{}{}
+	}
+
+	for path, value := range values {
+		keys := strings.Split(path, ".")
+		m := fields
+		for _, key := range keys[:len(keys)-1] {
+			next, ok := m[key].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				m[key] = next
+			}
+			m = next
+		}
+
+		m[keys[len(keys)-1]] = value
+	}
+
+	encoded, err := yaml.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("failed to set answers: %w", err)
+	}
+	// Values read from files may hold settings answers do not have
+	decoder := yaml.NewDecoder(bytes.NewReader(encoded))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(answers); err != nil {
+		return fmt.Errorf("failed to set answers: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ListTemplates returns the action of the list command, which prints every
+// registered template
+func ListTemplates(reg *Registry) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCOMMAND\tVERSION\tSOURCE\tDESCRIPTION")
+		for _, t := range reg.Templates() {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.Name, t.Command, t.Version, t.Source, t.Description)
+		}
+		return w.Flush()
+	}
+}
+
+// DescribeTemplate returns the action of the describe command, which prints
+// the options of a template, their allowed values and the dependencies each
+// value adds
+func DescribeTemplate(reg *Registry) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			return fmt.Errorf("expected exactly one template name or command")
+		}
+
+		t, ok := reg.Find(c.Args().First())
+		if !ok {
+			return fmt.Errorf("unknown template %s; run pytgen list to see the available templates", c.Args().First())
+		}
+
+		describeTemplate(t)
+		return nil
+	}
+}
+
+// describeTemplate prints the description of t
+func describeTemplate(t *Template) {
+	fmt.Printf("%s %s (%s)\n", t.Name, t.Version, t.Source)
+	if t.Description != "" {
+		fmt.Printf("%s: %s\n", t.ProjectType, t.Description)
+	}
+	fmt.Println()
+	fmt.Printf("Usage: pytgen %s [options]\n\n", t.Command)
+
+	fmt.Println("Options:")
+	describeFlag("name", "n", "Project name", t.DefaultName)
+	for _, v := range t.Variables {
+		describeFlag(v.Name, v.Alias, v.Description, v.Default)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, c := range v.Options {
+			deps := ""
+			if len(c.Dependencies) > 0 {
+				deps = "adds " + strings.Join(c.Dependencies, ", ")
+			}
+			fmt.Fprintf(w, "      %s\t%s\t%s\n", c.Name, c.Description, deps)
+		}
+		w.Flush()
+
+		for _, c := range v.Options {
+			if len(c.Requires) > 0 {
+				fmt.Printf("      %s needs %s\n", c.Name, describeRequirements(c.Requires))
+			}
+			for _, f := range c.Requires {
+				for _, value := range f.Options {
+					if deps := f.Dependencies[value]; len(deps) > 0 {
+						fmt.Printf("      %s with %s %s adds %s\n", c.Name, f.Field, value, strings.Join(deps, ", "))
+					}
+				}
+			}
+		}
+	}
+	for _, f := range t.ConfigFlags() {
+		description := fmt.Sprintf("%s, sets %s", f.Description, f.Field)
+		if len(f.Options) > 0 {
+			description += " (" + strings.Join(f.Options, ", ") + ")"
+		}
+		describeFlag(f.Flag, "", description, "")
+	}
+	describeFlag("venv", "", "Initialize a virtual environment and install the dependencies", "")
+	describeFlag("answers", "a", "Read answers from a YAML or JSON file", "")
+
+	if len(t.Dependencies) > 0 {
+		fmt.Printf("\nEvery project depends on: %s\n", strings.Join(t.Dependencies, ", "))
+	}
+}
+
+// describeFlag prints a flag with its description and default
+func describeFlag(name, alias, description, def string) {
+	flag := "--" + name
+	if alias != "" {
+		flag += ", -" + alias
+	}
+	if def != "" {
+		description = strings.TrimSpace(fmt.Sprintf("%s (default: %s)", description, def))
+	}
+	fmt.Printf("  %s\n      %s\n", flag, description)
+}
+
+// describeRequirements lists the answer fields a component needs with their
+// allowed values
+func describeRequirements(fields []ConfigField) string {
+	var parts []string
+	for _, f := range fields {
+		part := f.Field
+		if len(f.Options) > 0 {
+			part += " (" + strings.Join(f.Options, ", ") + ")"
+		}
+		if f.Optional {
+			part += " [optional]"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,301 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// diffOp is one line of an edit script
+type diffOp struct {
+	Kind byte // ' ' unchanged, '-' removed, '+' added
+	Line string
+}
+
+// splitLines splits content into lines without their trailing newlines
+func splitLines(content []byte) []string {
+	text := string(content)
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes a line edit script turning a into b using the longest
+// common subsequence of their lines
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+
+	return ops
+}
+
+// unifiedDiff returns the changes from a to b in unified diff format, or an
+// empty string if they are equal
+func unifiedDiff(aName, bName string, a, b []byte) string {
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	// aLine and bLine are the 1-based line numbers before ops[k]
+	aLine, bLine := 1, 1
+	for k := 0; k < len(ops); {
+		if ops[k].Kind == ' ' {
+			aLine++
+			bLine++
+			k++
+			continue
+		}
+
+		// Grow the hunk until diffContext*2 unchanged lines separate changes
+		start := k - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := k
+		for end < len(ops) {
+			if ops[end].Kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > diffContext*2 {
+				end += min(diffContext, run-end)
+				break
+			}
+			end = run
+		}
+
+		// Line numbers at the start of the hunk
+		hunkA, hunkB := aLine-(k-start), bLine-(k-start)
+		countA, countB := 0, 0
+		for _, op := range ops[start:end] {
+			if op.Kind != '+' {
+				countA++
+			}
+			if op.Kind != '-' {
+				countB++
+			}
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", aName, bName)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(hunkA, countA), hunkRange(hunkB, countB))
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(&out, "%c%s\n", op.Kind, op.Line)
+		}
+
+		// Advance the line counters past the hunk
+		for _, op := range ops[k:end] {
+			if op.Kind != '+' {
+				aLine++
+			}
+			if op.Kind != '-' {
+				bLine++
+			}
+		}
+		k = end
+	}
+
+	return out.String()
+}
+
+// hunkRange formats the start,count pair of a hunk header
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// An empty range names the line before it
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// min returns the smaller of two ints
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// diffHunk replaces the base lines [Start, End) with Lines
+type diffHunk struct {
+	Start, End int
+	Lines      []string
+}
+
+// diffHunks groups the edit script turning base into other into hunks
+func diffHunks(base, other []string) []diffHunk {
+	var hunks []diffHunk
+	i := 0
+	ops := diffLines(base, other)
+	for k := 0; k < len(ops); {
+		if ops[k].Kind == ' ' {
+			i++
+			k++
+			continue
+		}
+
+		h := diffHunk{Start: i}
+		for ; k < len(ops) && ops[k].Kind != ' '; k++ {
+			if ops[k].Kind == '-' {
+				i++
+			} else {
+				h.Lines = append(h.Lines, ops[k].Line)
+			}
+		}
+		h.End = i
+		hunks = append(hunks, h)
+	}
+	return hunks
+}
+
+// applyHunks applies hunks lying within base[start:end] to that range
+func applyHunks(base []string, start, end int, hunks []diffHunk) []string {
+	var out []string
+	i := start
+	for _, h := range hunks {
+		out = append(out, base[i:h.Start]...)
+		out = append(out, h.Lines...)
+		i = h.End
+	}
+	return append(out, base[i:end]...)
+}
+
+// merge3 merges the changes made to base in ours and in theirs. It reports
+// false if both changed the same lines differently.
+func merge3(base, ours, theirs []string) ([]string, bool) {
+	a, b := diffHunks(base, ours), diffHunks(base, theirs)
+
+	var out []string
+	i := 0 // next base line to copy
+	for len(a) > 0 || len(b) > 0 {
+		// Start a group at the earliest hunk and pull in every hunk from
+		// either side touching it
+		var groupA, groupB []diffHunk
+		var start, end int
+		if len(b) == 0 || (len(a) > 0 && a[0].Start <= b[0].Start) {
+			start, end = a[0].Start, a[0].End
+			groupA, a = a[:1], a[1:]
+		} else {
+			start, end = b[0].Start, b[0].End
+			groupB, b = b[:1], b[1:]
+		}
+		for {
+			if len(a) > 0 && a[0].Start <= end {
+				end = max(end, a[0].End)
+				groupA, a = append(groupA, a[0]), a[1:]
+			} else if len(b) > 0 && b[0].Start <= end {
+				end = max(end, b[0].End)
+				groupB, b = append(groupB, b[0]), b[1:]
+			} else {
+				break
+			}
+		}
+
+		out = append(out, base[i:start]...)
+		oursLines := applyHunks(base, start, end, groupA)
+		theirsLines := applyHunks(base, start, end, groupB)
+		switch {
+		case len(groupB) == 0:
+			out = append(out, oursLines...)
+		case len(groupA) == 0:
+			out = append(out, theirsLines...)
+		case equalLines(oursLines, theirsLines):
+			out = append(out, oursLines...)
+		default:
+			return nil, false
+		}
+		i = end
+	}
+
+	return append(out, base[i:]...), true
+}
+
+// mergeFiles merges the changes made to the file content base in ours and
+// in theirs line by line. The result ends in a newline if theirs changed
+// that of base to one, or else if ours does.
+func mergeFiles(base, ours, theirs string) (string, bool) {
+	merged, ok := merge3(splitLines([]byte(base)), splitLines([]byte(ours)), splitLines([]byte(theirs)))
+	if !ok {
+		return "", false
+	}
+	if len(merged) == 0 {
+		return "", true
+	}
+
+	newline := strings.HasSuffix(ours, "\n")
+	if base != "" && strings.HasSuffix(theirs, "\n") != strings.HasSuffix(base, "\n") {
+		newline = strings.HasSuffix(theirs, "\n")
+	}
+	content := strings.Join(merged, "\n")
+	if newline {
+		content += "\n"
+	}
+	return content, true
+}
+
+// equalLines reports whether a and b hold the same lines
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// max returns the larger of two ints
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,163 @@
+package templates
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lines splits a test text written with | between lines
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "|")
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string // ops as kind and line, separated by |
+	}{
+		{name: "equal", a: "a|b", b: "a|b", want: " a| b"},
+		{name: "insert at start", a: "a|b", b: "x|a|b", want: "+x| a| b"},
+		{name: "insert at end", a: "a|b", b: "a|b|x", want: " a| b|+x"},
+		{name: "replace", a: "a|b|c", b: "a|x|c", want: " a|-b|+x| c"},
+		{name: "from empty", a: "", b: "a", want: "+a"},
+		{name: "to empty", a: "a", b: "", want: "-a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, op := range diffLines(lines(tt.a), lines(tt.b)) {
+				got = append(got, string(op.Kind)+op.Line)
+			}
+			if strings.Join(got, "|") != tt.want {
+				t.Errorf("diffLines() = %q, want %q", strings.Join(got, "|"), tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyHunks(t *testing.T) {
+	base := lines("a|b|c|d")
+	hunks := diffHunks(base, lines("x|a|c|d|y"))
+
+	if got := applyHunks(base, 0, len(base), hunks); !reflect.DeepEqual(got, lines("x|a|c|d|y")) {
+		t.Errorf("applyHunks() = %q", got)
+	}
+	if got := applyHunks(base, 0, len(base), nil); !reflect.DeepEqual(got, base) {
+		t.Errorf("applyHunks() without hunks = %q, want the base", got)
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		conflict           bool
+	}{
+		{
+			name: "clean merge", base: "a|b|c|d|e",
+			ours: "a|B|c|d|e", theirs: "a|b|c|d|E",
+			want: "a|B|c|d|E",
+		},
+		{
+			name: "same change on both sides", base: "a|b|c",
+			ours: "a|x|c", theirs: "a|x|c",
+			want: "a|x|c",
+		},
+		{
+			name: "overlapping edits", base: "a|b|c",
+			ours: "a|x|c", theirs: "a|y|c",
+			conflict: true,
+		},
+		{
+			name: "insertion at the start", base: "a|b|c",
+			ours: "a|b|C", theirs: "x|a|b|c",
+			want: "x|a|b|C",
+		},
+		{
+			name: "insertion at the end", base: "a|b|c",
+			ours: "A|b|c", theirs: "a|b|c|x",
+			want: "A|b|c|x",
+		},
+		{
+			name: "insertions at the same place", base: "a|b",
+			ours: "a|b|x", theirs: "a|b|y",
+			conflict: true,
+		},
+		{
+			name: "deletion next to an edit", base: "a|b|c|d",
+			ours: "a|c|d", theirs: "a|b|c|D",
+			want: "a|c|D",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := merge3(lines(tt.base), lines(tt.ours), lines(tt.theirs))
+			if tt.conflict {
+				if ok {
+					t.Fatalf("merge3() = %q, want a conflict", got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("merge3() reported a conflict")
+			}
+			if strings.Join(got, "|") != tt.want {
+				t.Errorf("merge3() = %q, want %q", strings.Join(got, "|"), tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeFilesKeepsTheFinalNewline(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+	}{
+		{
+			name: "all end in a newline",
+			base: "a\nb\nc\n", ours: "A\nb\nc\n", theirs: "a\nb\nC\n",
+			want: "A\nb\nC\n",
+		},
+		{
+			name: "none end in a newline",
+			base: "a\nb\nc", ours: "A\nb\nc", theirs: "a\nb\nC",
+			want: "A\nb\nC",
+		},
+		{
+			name: "ours removed it",
+			base: "a\nb\nc\n", ours: "A\nb\nc", theirs: "a\nb\nC\n",
+			want: "A\nb\nC",
+		},
+		{
+			name: "theirs added it",
+			base: "a\nb\nc", ours: "A\nb\nc", theirs: "a\nb\nC\n",
+			want: "A\nb\nC\n",
+		},
+		{
+			name: "theirs removed it",
+			base: "a\nb\nc\n", ours: "A\nb\nc\n", theirs: "a\nb\nc",
+			want: "A\nb\nc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := mergeFiles(tt.base, tt.ours, tt.theirs)
+			if !ok {
+				t.Fatalf("mergeFiles() reported a conflict")
+			}
+			if got != tt.want {
+				t.Errorf("mergeFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
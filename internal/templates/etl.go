@@ -1,11 +1,16 @@
 package templates
 
 import (
+	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/urfave/cli/v2"
@@ -14,124 +19,815 @@ import (
 // ETLTemplateData holds data for ETL template generation
 type ETLTemplateData struct {
 	TemplateData
-	ExtractMethod   string
-	TransformMethod string
-	LoadDestination string
-	Dependencies    []string
+	ExtractMethod    string
+	TransformMethod  string
+	LoadDestination  string
+	ValidationMethod string
+	Engine           string
+	Orchestrator     string
+	Source           SourceConfig
+	Destination      DestinationConfig
+	TransformSpec    []TransformStep
+	Schema           *SampleSchema
+	Quality          QualityConfig
+	Orchestration    OrchestrationConfig
+	Dependencies     []string
 }
 
-// GenerateETLTemplate generates a Python ETL project template
-func GenerateETLTemplate(c *cli.Context) error {
-	projectName := c.String("name")
-	extractMethod := c.String("extract")
-	transformMethod := c.String("transform")
-	loadDestination := c.String("load")
-	createVenv := c.Bool("venv")
+// DatabaseConfig holds the connection details of a SQL database
+type DatabaseConfig struct {
+	Host   string `yaml:"host,omitempty" json:"host,omitempty"`
+	Port   string `yaml:"port,omitempty" json:"port,omitempty"`
+	Name   string `yaml:"name,omitempty" json:"name,omitempty"`
+	Path   string `yaml:"path,omitempty" json:"path,omitempty"`     // SQLite database file
+	URL    string `yaml:"url,omitempty" json:"url,omitempty"`       // SQLAlchemy URL of other engines
+	Schema string `yaml:"schema,omitempty" json:"schema,omitempty"` // empty for the default schema
+	Table  string `yaml:"table,omitempty" json:"table,omitempty"`
+}
 
-	// Convert project name to package name (lowercase, replace hyphens with underscores)
-	packageName := strings.ReplaceAll(strings.ToLower(projectName), "-", "_")
+// ObjectStorageConfig locates objects in S3-compatible object storage
+type ObjectStorageConfig struct {
+	Bucket   string `yaml:"bucket,omitempty" json:"bucket,omitempty"`
+	Prefix   string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
+	Region   string `yaml:"region,omitempty" json:"region,omitempty"`
+	Endpoint string `yaml:"endpoint,omitempty" json:"endpoint,omitempty"` // empty for AWS S3
+}
 
-	// Validate inputs
-	if err := validateETLInputs(extractMethod, transformMethod, loadDestination); err != nil {
+// QueueConfig holds the connection details of a message queue topic
+type QueueConfig struct {
+	Brokers string `yaml:"brokers,omitempty" json:"brokers,omitempty"` // comma-separated host:port list
+	Topic   string `yaml:"topic,omitempty" json:"topic,omitempty"`
+	GroupID string `yaml:"group_id,omitempty" json:"group_id,omitempty"`
+}
+
+// SFTPConfig holds the connection details of an SFTP server
+type SFTPConfig struct {
+	Host     string `yaml:"host,omitempty" json:"host,omitempty"`
+	Port     string `yaml:"port,omitempty" json:"port,omitempty"`
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+	Path     string `yaml:"path,omitempty" json:"path,omitempty"` // remote directory
+}
+
+// WarehouseConfig locates a table in a data warehouse. Which fields are used
+// depends on the engine.
+type WarehouseConfig struct {
+	Path     string `yaml:"path,omitempty" json:"path,omitempty"`         // DuckDB database file
+	Account  string `yaml:"account,omitempty" json:"account,omitempty"`   // Snowflake account identifier
+	Host     string `yaml:"host,omitempty" json:"host,omitempty"`         // Redshift cluster endpoint
+	Port     string `yaml:"port,omitempty" json:"port,omitempty"`         // Redshift port
+	Project  string `yaml:"project,omitempty" json:"project,omitempty"`   // BigQuery project
+	Database string `yaml:"database,omitempty" json:"database,omitempty"` // Snowflake or Redshift database
+	Schema   string `yaml:"schema,omitempty" json:"schema,omitempty"`     // schema, or BigQuery dataset
+	Table    string `yaml:"table,omitempty" json:"table,omitempty"`
+}
+
+// AuthConfig holds the non-secret settings of an API auth type. Secrets are
+// read from the environment by the generated code.
+type AuthConfig struct {
+	KeyName     string `yaml:"key_name,omitempty" json:"key_name,omitempty"`         // API key header or query parameter
+	KeyLocation string `yaml:"key_location,omitempty" json:"key_location,omitempty"` // header or query
+	TokenURL    string `yaml:"token_url,omitempty" json:"token_url,omitempty"`       // OAuth2 token endpoint
+	Scope       string `yaml:"scope,omitempty" json:"scope,omitempty"`               // OAuth2 scope
+}
+
+// PaginationConfig describes how an API splits its results into pages.
+// Which fields are used depends on the style.
+type PaginationConfig struct {
+	Style       string `yaml:"style,omitempty" json:"style,omitempty"`               // none, page, offset, cursor or link
+	PageSize    string `yaml:"page_size,omitempty" json:"page_size,omitempty"`       // records requested per page
+	PageParam   string `yaml:"page_param,omitempty" json:"page_param,omitempty"`     // page number, offset or cursor query parameter
+	SizeParam   string `yaml:"size_param,omitempty" json:"size_param,omitempty"`     // page size query parameter
+	CursorPath  string `yaml:"cursor_path,omitempty" json:"cursor_path,omitempty"`   // dotted path of the next cursor in a response
+	RecordsPath string `yaml:"records_path,omitempty" json:"records_path,omitempty"` // dotted path of the records in a response
+}
+
+// QualityConfig describes what the validation stage does with rows that
+// fail its checks
+type QualityConfig struct {
+	OnInvalid string `yaml:"on_invalid,omitempty" json:"on_invalid,omitempty"` // quarantine or fail
+	OutputDir string `yaml:"output_dir,omitempty" json:"output_dir,omitempty"` // rejected rows and the quality report
+}
+
+// OrchestrationConfig describes how an orchestrator schedules the pipeline
+// and retries its failed tasks
+type OrchestrationConfig struct {
+	Schedule   string `yaml:"schedule,omitempty" json:"schedule,omitempty"`       // cron expression
+	Retries    string `yaml:"retries,omitempty" json:"retries,omitempty"`         // retries of a failed task
+	RetryDelay string `yaml:"retry_delay,omitempty" json:"retry_delay,omitempty"` // seconds between retries
+}
+
+// RetryConfig describes how failed API requests are retried
+type RetryConfig struct {
+	Policy     string `yaml:"policy,omitempty" json:"policy,omitempty"` // none, fixed or exponential
+	MaxRetries string `yaml:"max_retries,omitempty" json:"max_retries,omitempty"`
+	Backoff    string `yaml:"backoff,omitempty" json:"backoff,omitempty"` // seconds before the first retry
+}
+
+// GraphQLConfig describes the query a GraphQL source is extracted with
+type GraphQLConfig struct {
+	Field     string `yaml:"field,omitempty" json:"field,omitempty"`         // root query field returning the records
+	Variables string `yaml:"variables,omitempty" json:"variables,omitempty"` // JSON object of query variables
+}
+
+// SOAPConfig describes the operation a SOAP source is extracted with. The
+// WSDL is located by the source URL.
+type SOAPConfig struct {
+	Operation   string `yaml:"operation,omitempty" json:"operation,omitempty"`
+	Arguments   string `yaml:"arguments,omitempty" json:"arguments,omitempty"`       // JSON object of operation arguments
+	RecordsPath string `yaml:"records_path,omitempty" json:"records_path,omitempty"` // dotted path of the records in the result
+}
+
+// SourceConfig describes where the pipeline extracts data from
+type SourceConfig struct {
+	Type       string              `yaml:"type,omitempty" json:"type,omitempty"`       // file type, API type or database engine
+	Pattern    string              `yaml:"pattern,omitempty" json:"pattern,omitempty"` // file path or glob pattern
+	Sheet      string              `yaml:"sheet,omitempty" json:"sheet,omitempty"`     // Excel sheet name or position, * for every sheet
+	URL        string              `yaml:"url,omitempty" json:"url,omitempty"`
+	AuthType   string              `yaml:"auth_type,omitempty" json:"auth_type,omitempty"`
+	Auth       AuthConfig          `yaml:"auth,omitempty" json:"auth,omitempty"`
+	Pagination PaginationConfig    `yaml:"pagination,omitempty" json:"pagination,omitempty"`
+	RateLimit  string              `yaml:"rate_limit,omitempty" json:"rate_limit,omitempty"` // requests per second
+	Retry      RetryConfig         `yaml:"retry,omitempty" json:"retry,omitempty"`
+	GraphQL    GraphQLConfig       `yaml:"graphql,omitempty" json:"graphql,omitempty"`
+	SOAP       SOAPConfig          `yaml:"soap,omitempty" json:"soap,omitempty"`
+	Database   DatabaseConfig      `yaml:"database,omitempty" json:"database,omitempty"`
+	Storage    ObjectStorageConfig `yaml:"storage,omitempty" json:"storage,omitempty"`
+	Queue      QueueConfig         `yaml:"queue,omitempty" json:"queue,omitempty"`
+	SFTP       SFTPConfig          `yaml:"sftp,omitempty" json:"sftp,omitempty"`
+}
+
+// DestinationConfig describes where the pipeline loads data to
+type DestinationConfig struct {
+	Type        string          `yaml:"type,omitempty" json:"type,omitempty"`             // file type, lake format or database engine
+	OutputDir   string          `yaml:"output_dir,omitempty" json:"output_dir,omitempty"` // output or lake table directory
+	URL         string          `yaml:"url,omitempty" json:"url,omitempty"`
+	Method      string          `yaml:"method,omitempty" json:"method,omitempty"`
+	AuthType    string          `yaml:"auth_type,omitempty" json:"auth_type,omitempty"`
+	Auth        AuthConfig      `yaml:"auth,omitempty" json:"auth,omitempty"`
+	WriteMode   string          `yaml:"write_mode,omitempty" json:"write_mode,omitempty"` // append, overwrite or upsert
+	PartitionBy []string        `yaml:"partition_by,omitempty" json:"partition_by,omitempty"`
+	KeyColumns  []string        `yaml:"key_columns,omitempty" json:"key_columns,omitempty"` // upsert or message keys
+	Database    DatabaseConfig  `yaml:"database,omitempty" json:"database,omitempty"`
+	Warehouse   WarehouseConfig `yaml:"warehouse,omitempty" json:"warehouse,omitempty"`
+	Queue       QueueConfig     `yaml:"queue,omitempty" json:"queue,omitempty"`
+}
+
+// ETLAnswers holds every choice needed to generate an ETL project. It is
+// filled by the command flags, an answers file or the interactive wizard.
+type ETLAnswers struct {
+	ProjectName       string              `yaml:"project_name" json:"project_name"`
+	ExtractMethod     string              `yaml:"extract" json:"extract"`
+	TransformMethod   string              `yaml:"transform" json:"transform"`
+	LoadDestination   string              `yaml:"load" json:"load"`
+	ValidationMethod  string              `yaml:"validation" json:"validation"`
+	Engine            string              `yaml:"engine" json:"engine"`
+	Orchestrator      string              `yaml:"orchestrator" json:"orchestrator"`
+	CreateVenv        bool                `yaml:"venv" json:"venv"`
+	Source            SourceConfig        `yaml:"source" json:"source"`
+	Destination       DestinationConfig   `yaml:"destination" json:"destination"`
+	TransformSpec     []TransformStep     `yaml:"transform_spec,omitempty" json:"transform_spec,omitempty"`
+	Quality           QualityConfig       `yaml:"quality,omitempty" json:"quality,omitempty"`
+	Orchestration     OrchestrationConfig `yaml:"orchestration,omitempty" json:"orchestration,omitempty"`
+	Sample            string              `yaml:"sample,omitempty" json:"sample,omitempty"` // dataset the schema is inferred from
+	Schema            *SampleSchema       `yaml:"schema,omitempty" json:"schema,omitempty"`
+	ExtraDependencies []string            `yaml:"extra_dependencies,omitempty" json:"extra_dependencies,omitempty"`
+}
+
+// fileExtensions maps the file types offered by the wizard to extensions
+var fileExtensions = map[string]string{
+	"CSV":        ".csv",
+	"Excel":      ".xlsx",
+	"JSON":       ".json",
+	"JSON Lines": ".jsonl",
+	"Parquet":    ".parquet",
+}
+
+// OutputPath returns the file the generated loader writes to by default
+func (d DestinationConfig) OutputPath() string {
+	ext, ok := fileExtensions[d.Type]
+	if !ok {
+		ext = ".csv"
+	}
+	return path.Join(filepath.ToSlash(d.OutputDir), "output"+ext)
+}
+
+// Extension returns the extension of the files of the source type
+func (s SourceConfig) Extension() string {
+	ext, ok := fileExtensions[s.Type]
+	if !ok {
+		ext = ".csv"
+	}
+	return ext
+}
+
+// sqlDialects maps database engines to the SQLAlchemy dialect and driver
+// the generated code connects with
+var sqlDialects = map[string]string{
+	"PostgreSQL": "postgresql+psycopg2",
+	"MySQL":      "mysql+pymysql",
+	"SQLite":     "sqlite",
+	"Oracle":     "oracle+oracledb",
+	"SQL Server": "mssql+pyodbc",
+}
+
+// defaultUsers maps database engines to their usual administrator account
+var defaultUsers = map[string]string{
+	"PostgreSQL": "postgres",
+	"MySQL":      "root",
+	"Oracle":     "system",
+	"SQL Server": "sa",
+}
+
+// Dialect returns the SQLAlchemy dialect and driver of a source database
+func (s SourceConfig) Dialect() string { return sqlDialects[s.Type] }
+
+// DefaultUser returns the user the generated extractor connects as by default
+func (s SourceConfig) DefaultUser() string { return defaultUsers[s.Type] }
+
+// Dialect returns the SQLAlchemy dialect and driver of a destination database
+func (d DestinationConfig) Dialect() string { return sqlDialects[d.Type] }
+
+// DefaultUser returns the user the generated loader connects as by default
+func (d DestinationConfig) DefaultUser() string { return defaultUsers[d.Type] }
+
+// GenerateETLTemplate generates a Python ETL project from template t. Values
+// come from the answers file given with --answers, if any, and flags set
+// explicitly on the command line take precedence over it.
+func GenerateETLTemplate(c *cli.Context, t *Template) error {
+	answers, err := etlAnswersFromFlags(c, t)
+	if err != nil {
+		return err
+	}
+
+	opts, err := generateOptions(c)
+	if err != nil {
+		return err
+	}
+	if err := generateETLProject(t, answers, opts); err != nil {
+		return err
+	}
+
+	if !opts.DryRun {
+		fmt.Printf("Python ETL project template generated successfully in %s\n", answers.ProjectName)
+	}
+	return nil
+}
+
+// etlAnswersFromFlags collects the answers given by the flags of the command
+// and the answers file given with --answers, if any
+func etlAnswersFromFlags(c *cli.Context, t *Template) (ETLAnswers, error) {
+	answers := ETLAnswers{
+		ProjectName:      c.String("name"),
+		ExtractMethod:    c.String("extract"),
+		TransformMethod:  c.String("transform"),
+		LoadDestination:  c.String("load"),
+		ValidationMethod: c.String("validation"),
+		Engine:           c.String("engine"),
+		Orchestrator:     c.String("orchestrator"),
+		CreateVenv:       c.Bool("venv"),
+	}
+
+	if path := c.String("answers"); path != "" {
+		fromFile, err := readETLAnswers(path, answers)
+		if err != nil {
+			return ETLAnswers{}, err
+		}
+
+		for flag, value := range map[string]*string{
+			"name":         &fromFile.ProjectName,
+			"extract":      &fromFile.ExtractMethod,
+			"transform":    &fromFile.TransformMethod,
+			"load":         &fromFile.LoadDestination,
+			"validation":   &fromFile.ValidationMethod,
+			"engine":       &fromFile.Engine,
+			"orchestrator": &fromFile.Orchestrator,
+		} {
+			if c.IsSet(flag) {
+				*value = c.String(flag)
+			}
+		}
+		if c.IsSet("venv") {
+			fromFile.CreateVenv = c.Bool("venv")
+		}
+		answers = fromFile
+	}
+	// A spec file implies the transform method it is read by
+	if c.IsSet("transform-spec") && !c.IsSet("transform") {
+		answers.TransformMethod = "spec"
+	}
+	if err := applyConfigFlags(c, t.Manifest, etlVars(answers), &answers); err != nil {
+		return ETLAnswers{}, err
+	}
+	// Settings given by neither get the defaults of the component catalog
+	if err := applyConfigDefaults(t.Manifest, etlVars(answers), &answers); err != nil {
+		return ETLAnswers{}, err
+	}
+	if err := completeETLAnswers(&answers); err != nil {
+		return ETLAnswers{}, err
+	}
+	return answers, nil
+}
+
+// readETLAnswers reads an answers file on top of base
+func readETLAnswers(path string, base ETLAnswers) (ETLAnswers, error) {
+	answers := base
+	if err := readAnswers(path, &answers); err != nil {
+		return ETLAnswers{}, err
+	}
+
+	// The wizard records no auth type when "None" is chosen
+	if answers.Source.AuthType == "None" {
+		answers.Source.AuthType = ""
+	}
+	if answers.Destination.AuthType == "None" {
+		answers.Destination.AuthType = ""
+	}
+	return answers, nil
+}
+
+// completeETLAnswers names the steps of the transformation spec of a and
+// infers the schema of its sample, if any
+func completeETLAnswers(a *ETLAnswers) error {
+	a.TransformSpec = withTransformDefaults(a.TransformSpec)
+	if a.Sample == "" {
+		return nil
+	}
+	schema, err := InferSchema(a.Sample)
+	if err != nil {
+		return err
+	}
+	a.Schema = schema
+	return nil
+}
+
+// generateETLProject validates answers and renders the project they describe
+func generateETLProject(t *Template, answers ETLAnswers, opts GenerateOptions) error {
+	p, err := renderETLProject(t, answers)
+	if err != nil {
 		return err
 	}
 
-	// Create project directory
-	if err := os.MkdirAll(projectName, 0755); err != nil {
-		return fmt.Errorf("failed to create project directory: %w", err)
+	return writeProject(p, opts)
+}
+
+// renderETLProject validates answers and renders the project they describe
+// in memory
+func renderETLProject(t *Template, answers ETLAnswers) (renderedProject, error) {
+	// Validate inputs
+	if err := validateETLAnswers(t, answers); err != nil {
+		return renderedProject{}, err
 	}
 
 	// Determine dependencies based on components
-	dependencies := determineDependencies(extractMethod, transformMethod, loadDestination)
+	dependencies := t.DependenciesFor(etlVars(answers), answers)
+	for _, dep := range answers.ExtraDependencies {
+		if !contains(dependencies, dep) {
+			dependencies = append(dependencies, dep)
+		}
+	}
 
 	// Prepare template data
 	data := ETLTemplateData{
 		TemplateData: TemplateData{
-			ProjectName:   projectName,
-			PackageName:   packageName,
+			ProjectName:   answers.ProjectName,
+			PackageName:   packageNameFor(answers.ProjectName),
 			Description:   "A Python ETL project for data processing",
 			PythonVersion: ">=3.8",
 		},
-		ExtractMethod:   extractMethod,
-		TransformMethod: transformMethod,
-		LoadDestination: loadDestination,
-		Dependencies:    dependencies,
+		ExtractMethod:    answers.ExtractMethod,
+		TransformMethod:  answers.TransformMethod,
+		LoadDestination:  answers.LoadDestination,
+		ValidationMethod: answers.ValidationMethod,
+		Engine:           answers.Engine,
+		Orchestrator:     answers.Orchestrator,
+		Source:           answers.Source,
+		Destination:      answers.Destination,
+		TransformSpec:    answers.TransformSpec,
+		Schema:           answers.Schema,
+		Quality:          answers.Quality,
+		Orchestration:    answers.Orchestration,
+		Dependencies:     dependencies,
+	}
+
+	// Render all template files
+	files, err := renderProjectFiles(t, etlVars(answers), data)
+	if err != nil {
+		return renderedProject{}, err
+	}
+
+	return renderedProject{
+		Template:     t,
+		Answers:      answers,
+		Dir:          answers.ProjectName,
+		Files:        files,
+		Dependencies: dependencies,
+		CreateVenv:   answers.CreateVenv,
+	}, nil
+}
+
+// renderETLFromLock re-renders an ETL project from the answers in its lock file
+func renderETLFromLock(t *Template, lock *Lock) (renderedProject, error) {
+	// Projects generated before validation, engines and orchestrators were
+	// offered have no validation, run on pandas and run as a plain script
+	answers := ETLAnswers{ValidationMethod: "none", Engine: "pandas", Orchestrator: "none"}
+	if err := json.Unmarshal(lock.Answers, &answers); err != nil {
+		return renderedProject{}, fmt.Errorf("invalid answers in %s: %w", LockFile, err)
 	}
+	return renderETLProject(t, answers)
+}
+
+// etlVars returns the methods chosen in answers keyed by manifest variable
+func etlVars(answers ETLAnswers) map[string]string {
+	return map[string]string{
+		"extract":      answers.ExtractMethod,
+		"transform":    answers.TransformMethod,
+		"load":         answers.LoadDestination,
+		"validation":   answers.ValidationMethod,
+		"engine":       answers.Engine,
+		"orchestrator": answers.Orchestrator,
+	}
+}
 
-	// Get all template files and directories
-	if err := generateProjectFiles(projectName, data); err != nil {
+// validateETLAnswers validates the methods against the component catalog of
+// t and the settings they need
+func validateETLAnswers(t *Template, a ETLAnswers) error {
+	if a.ProjectName == "" {
+		return fmt.Errorf("project name is required")
+	}
+
+	vars := etlVars(a)
+	if err := validateVariables(t, vars); err != nil {
+		return err
+	}
+	if err := checkRequirements(t.Manifest, vars, a); err != nil {
 		return err
 	}
 
-	// Initialize virtual environment if requested
-	if createVenv {
-		if err := initializeVirtualEnv(projectName); err != nil {
+	// Which connection details a database needs depends on its engine
+	if t.RequiresField(vars, "source.database.table") {
+		if err := validateDatabase("source", a.Source.Type, a.Source.Database); err != nil {
+			return err
+		}
+	}
+	if t.RequiresField(vars, "destination.database.table") {
+		if err := validateDatabase("destination", a.Destination.Type, a.Destination.Database); err != nil {
+			return err
+		}
+	}
+	if t.RequiresField(vars, "source.sftp.host") {
+		if err := validateHostname(a.Source.SFTP.Host); err != nil {
+			return fmt.Errorf("source sftp: %w", err)
+		}
+		if err := validatePort(a.Source.SFTP.Port); err != nil {
+			return fmt.Errorf("source sftp: %w", err)
+		}
+	}
+	if t.RequiresField(vars, "destination.warehouse.table") {
+		if err := validateWarehouse(a.Destination.Type, a.Destination.Warehouse); err != nil {
+			return err
+		}
+	}
+
+	if err := validateEngine(a); err != nil {
+		return err
+	}
+
+	if t.RequiresField(vars, "orchestration.schedule") {
+		if err := validateOrchestration(a.Orchestration); err != nil {
 			return err
 		}
 	}
 
-	fmt.Printf("Python ETL project template generated successfully in %s\n", projectName)
+	if a.Schema != nil {
+		if err := validateSchema(a.Schema); err != nil {
+			return err
+		}
+	}
+	if t.RequiresField(vars, "transform_spec") {
+		if err := validateTransformSpec(a.TransformSpec); err != nil {
+			return err
+		}
+	}
+	if t.RequiresField(vars, "source.sheet") && a.Source.Sheet != "" && a.Source.Type != "Excel" {
+		return fmt.Errorf("source sheet only applies to Excel files")
+	}
+	if t.RequiresField(vars, "source.pagination.style") {
+		if err := validatePagination(a.Source.Type, a.Source.Pagination); err != nil {
+			return err
+		}
+	}
+	if t.RequiresField(vars, "source.graphql.field") && a.Source.Type == "GraphQL" {
+		if err := validateName("source graphql field", a.Source.GraphQL.Field); err != nil {
+			return err
+		}
+		if err := validateJSONObject("source graphql variables", a.Source.GraphQL.Variables); err != nil {
+			return err
+		}
+	}
+	if t.RequiresField(vars, "source.soap.operation") && a.Source.Type == "SOAP" {
+		if err := validateName("source soap operation", a.Source.SOAP.Operation); err != nil {
+			return err
+		}
+		if err := validateJSONObject("source soap arguments", a.Source.SOAP.Arguments); err != nil {
+			return err
+		}
+		// A SOAP source is read with a single call
+		if a.Source.RateLimit != "" {
+			return fmt.Errorf("source rate_limit is not supported for SOAP APIs")
+		}
+	}
+	if t.RequiresField(vars, "source.rate_limit") {
+		if err := validateRateLimit(a.Source.RateLimit); err != nil {
+			return err
+		}
+	}
+	if t.RequiresField(vars, "source.retry.policy") {
+		if err := validateRetry(a.Source.Retry); err != nil {
+			return err
+		}
+	}
+	if t.RequiresField(vars, "source.auth_type") {
+		if err := validateAuth("source", a.Source.AuthType, a.Source.Auth); err != nil {
+			return err
+		}
+	}
+	if t.RequiresField(vars, "destination.auth_type") {
+		if err := validateAuth("destination", a.Destination.AuthType, a.Destination.Auth); err != nil {
+			return err
+		}
+	}
+
+	// An upsert replaces the rows matching the key columns
+	if t.RequiresField(vars, "destination.write_mode") && a.Destination.WriteMode == "upsert" && len(a.Destination.KeyColumns) == 0 {
+		return fmt.Errorf("destination key_columns are required to upsert")
+	}
+
+	return nil
+}
+
+// validatePagination checks the settings a pagination style of an API type
+// needs
+func validatePagination(apiType string, p PaginationConfig) error {
+	if p.Style == "" || p.Style == "none" {
+		return nil
+	}
+	if err := validatePageSize(p.PageSize); err != nil {
+		return err
+	}
+	if apiType == "GraphQL" {
+		return nil
+	}
+	if p.Style != "link" && p.PageParam == "" {
+		return fmt.Errorf("source pagination page_param is required for %s pagination", p.Style)
+	}
+	// The next cursor is read next to the records, so they cannot be the whole response
+	if p.Style == "cursor" && (p.CursorPath == "" || p.RecordsPath == "") {
+		return fmt.Errorf("source pagination cursor_path and records_path are required for cursor pagination")
+	}
 	return nil
 }
 
-// validateETLInputs validates the ETL input parameters
-func validateETLInputs(extract, transform, load string) error {
-	validExtractMethods := map[string]bool{"file": true, "api": true, "database": true}
-	validTransformMethods := map[string]bool{"basic": true, "advanced": true}
-	validLoadDestinations := map[string]bool{"file": true, "database": true, "api": true}
+// validateRateLimit checks a number of requests per second; empty means no limit
+func validateRateLimit(rate string) error {
+	if rate == "" {
+		return nil
+	}
+	if value, err := strconv.ParseFloat(rate, 64); err != nil || value <= 0 {
+		return fmt.Errorf("invalid source rate_limit: %q. Use a number of requests per second above 0", rate)
+	}
+	return nil
+}
 
-	if !validExtractMethods[extract] {
-		return fmt.Errorf("invalid extract method: %s. Valid options are: file, api, database", extract)
+// validateEngine checks that the engine of a supports the chosen extract,
+// transform, load and validation methods
+func validateEngine(a ETLAnswers) error {
+	if a.Engine != "pandas" && a.TransformMethod == "advanced" {
+		return fmt.Errorf("transform advanced needs the pandas engine")
+	}
+	// Only files are read and written by the engines themselves; the other
+	// connectors hand pandas DataFrames over
+	if a.Engine != "pandas" && a.ExtractMethod != "file" {
+		return fmt.Errorf("extract %s needs the pandas engine; the %s engine only extracts files", a.ExtractMethod, a.Engine)
 	}
+	if a.Engine != "pandas" && a.LoadDestination != "file" {
+		return fmt.Errorf("load %s needs the pandas engine; the %s engine only loads files", a.LoadDestination, a.Engine)
+	}
+	if a.Engine == "pyspark" && a.ValidationMethod != "none" {
+		return fmt.Errorf("validation %s checks pandas data and is not available with the pyspark engine", a.ValidationMethod)
+	}
+	return nil
+}
 
-	if !validTransformMethods[transform] {
-		return fmt.Errorf("invalid transform method: %s. Valid options are: basic, advanced", transform)
+// validateRetry checks the settings of a retry policy
+func validateRetry(r RetryConfig) error {
+	if r.Policy == "" || r.Policy == "none" {
+		return nil
 	}
+	if err := validateMaxRetries(r.MaxRetries); err != nil {
+		return err
+	}
+	return validateBackoff(r.Backoff)
+}
 
-	if !validLoadDestinations[load] {
-		return fmt.Errorf("invalid load destination: %s. Valid options are: file, database, api", load)
+// validateOrchestration checks the schedule and task retries of an
+// orchestrator
+func validateOrchestration(o OrchestrationConfig) error {
+	if err := validateSchedule(o.Schedule); err != nil {
+		return err
 	}
+	if err := validateTaskRetries(o.Retries); err != nil {
+		return err
+	}
+	return validateTaskRetryDelay(o.RetryDelay)
+}
 
+// validateSchedule checks that schedule is a cron expression
+func validateSchedule(schedule string) error {
+	if fields := strings.Fields(schedule); len(fields) != 5 {
+		return fmt.Errorf("invalid orchestration schedule: %q. Use a cron expression of 5 fields, e.g. 0 2 * * *", schedule)
+	}
 	return nil
 }
 
-// determineDependencies returns a list of package dependencies based on selected methods
-func determineDependencies(extract, transform, load string) []string {
-	dependencies := []string{"pytest", "python-dotenv"}
+// validateTaskRetries checks the retries of a failed pipeline task
+func validateTaskRetries(retries string) error {
+	if value, err := strconv.Atoi(retries); err != nil || value < 0 {
+		return fmt.Errorf("invalid orchestration retries: %q. Use a whole number", retries)
+	}
+	return nil
+}
 
-	// Extract dependencies
-	switch extract {
-	case "file":
-		dependencies = append(dependencies, "pandas")
-	case "api":
-		dependencies = append(dependencies, "requests")
-	case "database":
-		dependencies = append(dependencies, "sqlalchemy", "psycopg2-binary")
+// validateTaskRetryDelay checks the seconds between the retries of a failed
+// pipeline task
+func validateTaskRetryDelay(delay string) error {
+	if value, err := strconv.ParseFloat(delay, 64); err != nil || value < 0 {
+		return fmt.Errorf("invalid orchestration retry_delay: %q. Use a number of seconds", delay)
 	}
+	return nil
+}
+
+// namePattern matches the names GraphQL fields and SOAP operations share
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
 
-	// Transform dependencies
-	switch transform {
-	case "basic":
-		dependencies = append(dependencies, "pandas")
-	case "advanced":
-		dependencies = append(dependencies, "pandas", "numpy", "scikit-learn")
+// validateName checks a GraphQL field or SOAP operation name
+func validateName(what, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s is required", what)
+	}
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid %s: %q. Use letters, digits and underscores", what, name)
 	}
+	return nil
+}
+
+// validateJSONObject checks that an optional setting holds a JSON object
+func validateJSONObject(what, value string) error {
+	if value == "" {
+		return nil
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &object); err != nil {
+		return fmt.Errorf("invalid %s: %q. Use a JSON object such as {\"status\": \"active\"}", what, value)
+	}
+	return nil
+}
+
+// validatePageSize checks a number of records per page
+func validatePageSize(size string) error {
+	if value, err := strconv.Atoi(size); err != nil || value <= 0 {
+		return fmt.Errorf("invalid source pagination page_size: %q. Use a whole number above 0", size)
+	}
+	return nil
+}
+
+// validateMaxRetries checks a number of retries
+func validateMaxRetries(retries string) error {
+	if value, err := strconv.Atoi(retries); err != nil || value < 0 {
+		return fmt.Errorf("invalid source retry max_retries: %q. Use a whole number", retries)
+	}
+	return nil
+}
 
-	// Load dependencies
-	switch load {
-	case "file":
-		// pandas already added
-	case "database":
-		if !contains(dependencies, "sqlalchemy") {
-			dependencies = append(dependencies, "sqlalchemy", "psycopg2-binary")
+// validateBackoff checks a delay in seconds
+func validateBackoff(backoff string) error {
+	if value, err := strconv.ParseFloat(backoff, 64); err != nil || value < 0 {
+		return fmt.Errorf("invalid source retry backoff: %q. Use a number of seconds", backoff)
+	}
+	return nil
+}
+
+// validateAuth checks the settings an API auth type needs
+func validateAuth(role, authType string, auth AuthConfig) error {
+	switch authType {
+	case "API Key":
+		if auth.KeyName == "" {
+			return fmt.Errorf("%s auth key_name is required for API Key auth", role)
+		}
+		if auth.KeyLocation != "header" && auth.KeyLocation != "query" {
+			return fmt.Errorf("invalid %s auth key_location: %q. Use header or query", role, auth.KeyLocation)
 		}
-	case "api":
-		if !contains(dependencies, "requests") {
-			dependencies = append(dependencies, "requests")
+	case "OAuth2":
+		if auth.TokenURL == "" {
+			return fmt.Errorf("%s auth token_url is required for OAuth2", role)
 		}
 	}
+	return nil
+}
 
-	return dependencies
+// validateWarehouse checks the connection details of a destination warehouse
+func validateWarehouse(engine string, w WarehouseConfig) error {
+	var missing []string
+	require := func(name, value string) {
+		if value == "" {
+			missing = append(missing, name)
+		}
+	}
+
+	switch engine {
+	case "DuckDB":
+		require("path", w.Path)
+	case "Snowflake":
+		require("account", w.Account)
+		require("database", w.Database)
+		require("schema", w.Schema)
+	case "BigQuery":
+		require("project", w.Project)
+		require("schema", w.Schema)
+	case "Redshift":
+		require("host", w.Host)
+		require("port", w.Port)
+		require("database", w.Database)
+		require("schema", w.Schema)
+	}
+	require("table", w.Table)
+
+	if len(missing) > 0 {
+		return fmt.Errorf("destination %s warehouse needs %s", engine, strings.Join(missing, ", "))
+	}
+	if engine == "Redshift" {
+		if err := validateHostname(w.Host); err != nil {
+			return fmt.Errorf("destination warehouse: %w", err)
+		}
+		if err := validatePort(w.Port); err != nil {
+			return fmt.Errorf("destination warehouse: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateDatabase checks the connection details of a source or destination database
+func validateDatabase(role, engine string, db DatabaseConfig) error {
+	if engine == "SQLite" {
+		if db.Path == "" {
+			return fmt.Errorf("%s SQLite database path is required", role)
+		}
+	} else if engine == "Other" {
+		if db.URL == "" {
+			return fmt.Errorf("%s database url is required for other engines", role)
+		}
+	} else if db.Host == "" || db.Port == "" || db.Name == "" {
+		return fmt.Errorf("%s database host, port and name are required", role)
+	} else if err := validateHostname(db.Host); err != nil {
+		return fmt.Errorf("%s database: %w", role, err)
+	} else if err := validatePort(db.Port); err != nil {
+		return fmt.Errorf("%s database: %w", role, err)
+	}
+
+	if db.Table == "" {
+		return fmt.Errorf("%s table name is required", role)
+	}
+	return nil
+}
+
+// hostnamePattern matches a DNS host name
+var hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*\.?$`)
+
+// validateHostname checks that host is a host name or an IP address
+func validateHostname(host string) error {
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	if len(host) > 253 || !hostnamePattern.MatchString(host) {
+		return fmt.Errorf("invalid hostname: %q", host)
+	}
+	return nil
+}
+
+// validatePort checks that port is a TCP port number
+func validatePort(port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port: %q. Ports are numbers from 1 to 65535", port)
+	}
+	return nil
+}
+
+// validateOption checks that value is one of options
+func validateOption(what, value string, options []string) error {
+	if !contains(options, value) {
+		return fmt.Errorf("invalid %s: %s. Valid options are: %s", what, value, strings.Join(options, ", "))
+	}
+	return nil
 }
 
 // contains checks if a string is in a slice
@@ -144,40 +840,6 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-// generateProjectFiles generates all project files from templates
-func generateProjectFiles(projectName string, data ETLTemplateData) error {
-	templateDir := "templates/etl-python"
-
-	// Define the mapping of template files to destination paths
-	templatesMap := map[string]string{
-		"README.md.tmpl":                  filepath.Join(projectName, "README.md"),
-		"requirements.txt.tmpl":           filepath.Join(projectName, "requirements.txt"),
-		"setup.py.tmpl":                   filepath.Join(projectName, "setup.py"),
-		".gitignore.tmpl":                 filepath.Join(projectName, ".gitignore"),
-		"src/__init__.py.tmpl":            filepath.Join(projectName, "src", "__init__.py"),
-		"src/main.py.tmpl":                filepath.Join(projectName, "src", "main.py"),
-		"src/extract/__init__.py.tmpl":    filepath.Join(projectName, "src", "extract", "__init__.py"),
-		"src/extract/extract.py.tmpl":     filepath.Join(projectName, "src", "extract", "extract.py"),
-		"src/transform/__init__.py.tmpl":  filepath.Join(projectName, "src", "transform", "__init__.py"),
-		"src/transform/transform.py.tmpl": filepath.Join(projectName, "src", "transform", "transform.py"),
-		"src/load/__init__.py.tmpl":       filepath.Join(projectName, "src", "load", "__init__.py"),
-		"src/load/load.py.tmpl":           filepath.Join(projectName, "src", "load", "load.py"),
-		"tests/__init__.py.tmpl":          filepath.Join(projectName, "tests", "__init__.py"),
-		"tests/test_extract.py.tmpl":      filepath.Join(projectName, "tests", "test_extract.py"),
-		"tests/test_transform.py.tmpl":    filepath.Join(projectName, "tests", "test_transform.py"),
-		"tests/test_load.py.tmpl":         filepath.Join(projectName, "tests", "test_load.py"),
-	}
-
-	// Render each template
-	for tmpl, dest := range templatesMap {
-		if err := RenderTemplate(filepath.Join(templateDir, tmpl), dest, data); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 // initializeVirtualEnv creates and initializes a Python virtual environment
 func initializeVirtualEnv(projectDir string) error {
 	fmt.Println("Initializing Python virtual environment...")
@@ -0,0 +1,154 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testTransformSpec uses every step type of a transformation spec
+const testTransformSpec = `- type: rename
+  columns: {Name: name}
+- type: cast
+  columns: {amount: float, created: datetime}
+- type: filter
+  column: amount
+  op: ">"
+  value: "0"
+- type: filter
+  column: country
+  op: in
+  value: US,CA
+- type: dedupe
+  keys: [id]
+  keep: last
+- type: fill
+  columns: {amount: median}
+  values: {country: unknown}
+- type: derive
+  column: total
+  expr: amount * 2
+- type: join
+  path: data/countries.csv
+  keys: [country]
+  how: left
+- type: aggregate
+  keys: [country]
+  metrics: {total: sum(amount), orders: count(id)}
+`
+
+func TestRenderETLProjectCombinations(t *testing.T) {
+	tmpl := builtinTemplate(t, "etl-python")
+	spec := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(spec, []byte(testTransformSpec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	values := func(name string) []string {
+		v, ok := tmpl.Variable(name)
+		if !ok {
+			t.Fatalf("variable %s is missing", name)
+		}
+		return v.Values()
+	}
+
+	type combination struct {
+		extract, transform, load, validation, engine, orchestrator string
+	}
+	var tests []combination
+	for _, extract := range values("extract") {
+		for _, transform := range values("transform") {
+			for _, load := range values("load") {
+				for _, validation := range values("validation") {
+					for _, engine := range values("engine") {
+						for _, orchestrator := range values("orchestrator") {
+							tests = append(tests, combination{extract, transform, load, validation, engine, orchestrator})
+						}
+					}
+				}
+			}
+		}
+	}
+
+	rendered := 0
+	for _, tt := range tests {
+		answers := ETLAnswers{
+			ExtractMethod:    tt.extract,
+			TransformMethod:  tt.transform,
+			LoadDestination:  tt.load,
+			ValidationMethod: tt.validation,
+			Engine:           tt.engine,
+		}
+		if validateEngine(answers) != nil {
+			continue
+		}
+		rendered++
+
+		name := tt.extract + "-" + tt.transform + "-" + tt.load + "-" + tt.validation + "-" + tt.engine + "-" + tt.orchestrator
+		t.Run(name, func(t *testing.T) {
+			args := []string{
+				"--name", "pipeline",
+				"--extract", tt.extract,
+				"--transform", tt.transform,
+				"--load", tt.load,
+				"--validation", tt.validation,
+				"--engine", tt.engine,
+				"--orchestrator", tt.orchestrator,
+			}
+			if tt.transform == "spec" {
+				args = append(args, "--transform-spec", spec)
+			}
+			answers, err := etlAnswersFromFlags(commandContext(t, tmpl, args), tmpl)
+			if err != nil {
+				t.Fatalf("etlAnswersFromFlags() error = %v", err)
+			}
+			p, err := renderETLProject(tmpl, answers)
+			if err != nil {
+				t.Fatalf("renderETLProject() error = %v", err)
+			}
+
+			files := map[string]int{}
+			for _, file := range p.Files {
+				files[file.Path]++
+			}
+			for path, n := range files {
+				if n > 1 {
+					t.Errorf("%s is rendered %d times", path, n)
+				}
+			}
+
+			want := map[string]bool{
+				"README.md":                   true,
+				"requirements.txt":            true,
+				"setup.py":                    true,
+				"src/main.py":                 true,
+				"src/extract/extract.py":      true,
+				"tests/test_extract.py":       true,
+				"src/transform/transform.py":  true,
+				"tests/test_transform.py":     true,
+				"src/load/load.py":            true,
+				"tests/test_load.py":          true,
+				"src/engine.py":               tt.engine != "pandas",
+				"src/validate/validate.py":    tt.validation != "none",
+				"src/validate/report.py":      tt.validation != "none",
+				"tests/test_validate.py":      tt.validation != "none",
+				"dags/etl_dag.py":             tt.orchestrator == "airflow",
+				"src/flow.py":                 tt.orchestrator == "prefect",
+				"src/definitions.py":          tt.orchestrator == "dagster",
+				"src/auth.py":                 tt.extract == "api" || tt.load == "api",
+				"src/models.py":               false,
+				"src/extract/query.graphql":   false,
+				"tests/fixtures/service.wsdl": false,
+			}
+			for path, exists := range want {
+				if got := files[path] > 0; got != exists {
+					t.Errorf("%s rendered = %v, want %v", path, got, exists)
+				}
+			}
+		})
+	}
+
+	if rendered == 0 {
+		t.Fatal("no valid combination was rendered")
+	}
+}
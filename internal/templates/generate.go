@@ -0,0 +1,358 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ConflictPolicy decides what happens to generated files that already exist
+// with different content
+type ConflictPolicy string
+
+const (
+	ConflictFail      ConflictPolicy = "fail"
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	ConflictPrompt    ConflictPolicy = "prompt"
+)
+
+// ConflictPolicies lists the valid conflict policies
+var ConflictPolicies = []string{
+	string(ConflictFail),
+	string(ConflictSkip),
+	string(ConflictOverwrite),
+	string(ConflictPrompt),
+}
+
+// GenerateOptions controls how a rendered project is written out
+type GenerateOptions struct {
+	DryRun     bool // print the file plan instead of writing it
+	OnConflict ConflictPolicy
+}
+
+// generateOptions reads the generation flags shared by all commands
+func generateOptions(c *cli.Context) (GenerateOptions, error) {
+	opts := GenerateOptions{
+		DryRun:     c.Bool("dry-run"),
+		OnConflict: ConflictPolicy(c.String("on-conflict")),
+	}
+
+	if opts.OnConflict == "" {
+		opts.OnConflict = ConflictFail
+	}
+	if err := validateOption("conflict policy", string(opts.OnConflict), ConflictPolicies); err != nil {
+		return GenerateOptions{}, err
+	}
+
+	return opts, nil
+}
+
+// applyConfigFlags sets the answer fields of the config flags of m given on
+// the command line. The fields must be required by the components chosen by
+// vars. answers must be a pointer.
+func applyConfigFlags(c *cli.Context, m Manifest, vars map[string]string, answers interface{}) error {
+	values := map[string]interface{}{}
+	for _, f := range m.ConfigFlags() {
+		if !c.IsSet(f.Flag) {
+			continue
+		}
+		if !m.RequiresField(vars, f.Field) {
+			return fmt.Errorf("--%s does not apply to the chosen options", f.Flag)
+		}
+		if !f.File {
+			values[f.Field] = c.String(f.Flag)
+			continue
+		}
+
+		// Set on their own so that mistakes in the file are reported with it
+		value, err := readFlagFile(f.Flag, c.String(f.Flag))
+		if err != nil {
+			return err
+		}
+		if err := setAnswerFields(answers, map[string]interface{}{f.Field: value}); err != nil {
+			return fmt.Errorf("invalid --%s file %s: %v", f.Flag, c.String(f.Flag), err)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return setAnswerFields(answers, values)
+}
+
+// readFlagFile decodes the YAML or JSON file given to flag
+func readFlagFile(flag, path string) (interface{}, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --%s file: %w", flag, err)
+	}
+
+	var value interface{}
+	if err := yaml.Unmarshal(content, &value); err != nil {
+		return nil, fmt.Errorf("failed to parse --%s file %s: %v", flag, path, err)
+	}
+	return value, nil
+}
+
+// RenderedFile is a rendered template and its slash-separated path inside
+// the project
+type RenderedFile struct {
+	Path    string
+	Content []byte
+}
+
+// renderedProject is a project rendered in memory, ready to be written
+type renderedProject struct {
+	Template     *Template
+	Answers      interface{} // recorded in the lock file
+	Dir          string
+	Files        []RenderedFile
+	Dependencies []string
+	CreateVenv   bool
+}
+
+// renderProjectFiles renders the files of the template manifest and of the
+// components chosen by vars whose conditions hold for data
+func renderProjectFiles(t *Template, vars map[string]string, data interface{}) ([]RenderedFile, error) {
+	files, err := planFiles(t, vars, data)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := make([]RenderedFile, 0, len(files))
+	for _, file := range files {
+		content, err := renderTemplate(t.FS, file.Src, data)
+		if err != nil {
+			return nil, err
+		}
+		rendered = append(rendered, RenderedFile{Path: file.Dest, Content: content})
+	}
+
+	return rendered, nil
+}
+
+// writeProject writes a rendered project to disk and initializes its virtual
+// environment if requested. Files are staged first and moved into place
+// together; if anything fails, including the virtual environment, the
+// project directory is restored. In a dry run it only prints the plan.
+func writeProject(p renderedProject, opts GenerateOptions) error {
+	conflicts, err := findConflicts(p)
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		printDryRun(p, conflicts)
+		return nil
+	}
+
+	files, err := resolveConflicts(p, conflicts, opts.OnConflict)
+	if err != nil {
+		return err
+	}
+
+	// The lock file is always rewritten so the project can be updated later.
+	// It records the files written; skipped files keep their previous base.
+	lock, err := newLock(p.Template, p.Answers, files, keptBases(p.Dir, p.Files, files))
+	if err != nil {
+		return err
+	}
+	files = append(files, lock)
+
+	staged, err := stageProject(p.Dir, files)
+	if err != nil {
+		return err
+	}
+	defer staged.cleanup()
+
+	if err := staged.commit(files); err != nil {
+		return err
+	}
+
+	// Initialize virtual environment if requested
+	if p.CreateVenv {
+		venvDir := filepath.Join(p.Dir, "venv")
+		_, statErr := os.Stat(venvDir)
+		venvExisted := statErr == nil
+
+		if err := initializeVirtualEnv(p.Dir); err != nil {
+			if !venvExisted {
+				os.RemoveAll(venvDir)
+			}
+			staged.rollback()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFile writes content to path, creating parent directories as needed
+func writeFile(path string, content []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to create file %s: %w", path, err)
+	}
+	return nil
+}
+
+// findConflicts returns the existing files of p, keyed by path, whose
+// content differs from the rendered one
+func findConflicts(p renderedProject) (map[string][]byte, error) {
+	conflicts := make(map[string][]byte)
+
+	for _, file := range p.Files {
+		path := filepath.Join(p.Dir, filepath.FromSlash(file.Path))
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", path, err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("cannot generate %s: a directory with that name exists", path)
+		}
+
+		existing, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if !bytes.Equal(existing, file.Content) {
+			conflicts[file.Path] = existing
+		}
+	}
+
+	return conflicts, nil
+}
+
+// resolveConflicts applies policy to the conflicting files of p and returns
+// the files to write
+func resolveConflicts(p renderedProject, conflicts map[string][]byte, policy ConflictPolicy) ([]RenderedFile, error) {
+	if len(conflicts) == 0 || policy == ConflictOverwrite {
+		return p.Files, nil
+	}
+
+	if policy == ConflictFail {
+		var paths []string
+		for path := range conflicts {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		return nil, fmt.Errorf("%d file(s) in %s already exist with different content:\n  %s\nuse --on-conflict=skip|overwrite|prompt to continue",
+			len(paths), p.Dir, strings.Join(paths, "\n  "))
+	}
+
+	var files []RenderedFile
+	for _, file := range p.Files {
+		existing, conflict := conflicts[file.Path]
+		if !conflict {
+			files = append(files, file)
+			continue
+		}
+
+		write := false
+		if policy == ConflictPrompt {
+			var err error
+			write, err = promptConflict(file, existing)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if write {
+			files = append(files, file)
+		} else {
+			fmt.Printf("Skipped existing file %s\n", file.Path)
+		}
+	}
+
+	return files, nil
+}
+
+// askOne and askAll ask survey questions; tests replace them to answer
+// prompts
+var (
+	askOne = survey.AskOne
+	askAll = survey.Ask
+)
+
+// promptConflict shows the diff between an existing file and its rendered
+// replacement and asks whether to overwrite it
+func promptConflict(file RenderedFile, existing []byte) (bool, error) {
+	fmt.Printf("\n%s already exists:\n", file.Path)
+	fmt.Print(unifiedDiff("a/"+file.Path, "b/"+file.Path, existing, file.Content))
+
+	overwrite := false
+	prompt := &survey.Confirm{
+		Message: fmt.Sprintf("Overwrite %s?", file.Path),
+		Default: false,
+	}
+	if err := askOne(prompt, &overwrite); err != nil {
+		return false, err
+	}
+	return overwrite, nil
+}
+
+// printDryRun prints the file tree, file sizes and dependencies of p,
+// marking the files that conflict with existing ones
+func printDryRun(p renderedProject, conflicts map[string][]byte) {
+	fmt.Printf("Dry run: nothing will be written.\n\n")
+
+	files := append([]RenderedFile(nil), p.Files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	fmt.Printf("%s/\n", p.Dir)
+	printedDirs := make(map[string]bool)
+	total := 0
+	for _, file := range files {
+		parts := strings.Split(file.Path, "/")
+		for depth := 1; depth < len(parts); depth++ {
+			dir := strings.Join(parts[:depth], "/")
+			if !printedDirs[dir] {
+				printedDirs[dir] = true
+				fmt.Printf("%s%s/\n", strings.Repeat("  ", depth), parts[depth-1])
+			}
+		}
+		marker := ""
+		if _, ok := conflicts[file.Path]; ok {
+			marker = " [conflict]"
+		}
+		fmt.Printf("%s%s (%s)%s\n", strings.Repeat("  ", len(parts)), parts[len(parts)-1], formatSize(len(file.Content)), marker)
+		total += len(file.Content)
+	}
+	fmt.Printf("\n%d files, %s\n", len(files), formatSize(total))
+	if len(conflicts) > 0 {
+		fmt.Printf("%d file(s) conflict with existing files\n", len(conflicts))
+	}
+
+	if len(p.Dependencies) > 0 {
+		fmt.Println("\nDependencies:")
+		for _, dep := range p.Dependencies {
+			fmt.Printf("  - %s\n", dep)
+		}
+	}
+
+	if p.CreateVenv {
+		fmt.Println("\nA virtual environment would be initialized.")
+	}
+}
+
+// formatSize formats a byte count for humans
+func formatSize(n int) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	return fmt.Sprintf("%.1f KB", float64(n)/1024)
+}
@@ -0,0 +1,418 @@
+package templates
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/core"
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/urfave/cli/v2"
+)
+
+func TestWriteProjectRollsBackAfterVenvFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing map[string]string
+	}{
+		{name: "new project"},
+		{name: "existing project", existing: map[string]string{"a.txt": "old\n", "notes.txt": "mine\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Without python on the PATH the virtual environment cannot be created
+			t.Setenv("PATH", t.TempDir())
+
+			dir := filepath.Join(t.TempDir(), "project")
+			for path, content := range tt.existing {
+				if err := writeFile(filepath.Join(dir, filepath.FromSlash(path)), []byte(content)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			p := renderedProject{
+				Template:   &Template{Manifest: Manifest{Name: "test", Version: "1"}},
+				Dir:        dir,
+				Files:      []RenderedFile{{Path: "a.txt", Content: []byte("a\n")}, {Path: "src/b.py", Content: []byte("b\n")}},
+				CreateVenv: true,
+			}
+			err := writeProject(p, GenerateOptions{OnConflict: ConflictOverwrite})
+			if err == nil || !strings.Contains(err.Error(), "virtual environment") {
+				t.Fatalf("writeProject() error = %v, want a virtual environment failure", err)
+			}
+
+			if tt.existing == nil {
+				if _, err := os.Stat(dir); !os.IsNotExist(err) {
+					t.Errorf("the new project directory was left behind")
+				}
+				return
+			}
+			assertFiles(t, dir, tt.existing)
+		})
+	}
+}
+
+func TestResolveConflicts(t *testing.T) {
+	p := renderedProject{
+		Dir: "project",
+		Files: []RenderedFile{
+			{Path: "a.txt", Content: []byte("a2\n")},
+			{Path: "b.txt", Content: []byte("b2\n")},
+			{Path: "c.txt", Content: []byte("c\n")},
+		},
+	}
+	conflicts := map[string][]byte{"a.txt": []byte("a1\n"), "b.txt": []byte("b1\n")}
+
+	tests := []struct {
+		name      string
+		conflicts map[string][]byte
+		policy    ConflictPolicy
+		overwrite string // file the prompt agrees to overwrite
+		promptErr error
+		want      []string
+		wantErr   string
+	}{
+		{name: "no conflicts", policy: ConflictFail, want: []string{"a.txt", "b.txt", "c.txt"}},
+		{name: "fail", conflicts: conflicts, policy: ConflictFail, wantErr: "2 file(s) in project already exist"},
+		{name: "skip", conflicts: conflicts, policy: ConflictSkip, want: []string{"c.txt"}},
+		{name: "overwrite", conflicts: conflicts, policy: ConflictOverwrite, want: []string{"a.txt", "b.txt", "c.txt"}},
+		{name: "prompt", conflicts: conflicts, policy: ConflictPrompt, overwrite: "b.txt", want: []string{"b.txt", "c.txt"}},
+		{name: "interrupted prompt", conflicts: conflicts, policy: ConflictPrompt, promptErr: terminal.InterruptErr, wantErr: "interrupt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubAskOne(t, func(prompt survey.Prompt, response interface{}) error {
+				if tt.promptErr != nil {
+					return tt.promptErr
+				}
+				message := prompt.(*survey.Confirm).Message
+				*response.(*bool) = message == "Overwrite "+tt.overwrite+"?"
+				return nil
+			})
+
+			files, err := resolveConflicts(p, tt.conflicts, tt.policy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveConflicts() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveConflicts() error = %v", err)
+			}
+
+			var got []string
+			for _, file := range files {
+				got = append(got, file.Path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("files = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// stubAskOne answers the survey questions of the test with answer
+func stubAskOne(t *testing.T, answer func(prompt survey.Prompt, response interface{}) error) {
+	t.Helper()
+
+	original := askOne
+	askOne = func(prompt survey.Prompt, response interface{}, _ ...survey.AskOpt) error {
+		return answer(prompt, response)
+	}
+	t.Cleanup(func() { askOne = original })
+}
+
+func TestWriteProjectDryRun(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "project")
+	if err := writeFile(filepath.Join(dir, "src", "main.py"), []byte("print('mine')\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	p := renderedProject{
+		Template: &Template{Manifest: Manifest{Name: "test", Version: "1"}},
+		Dir:      dir,
+		Files: []RenderedFile{
+			{Path: "src/main.py", Content: []byte("print('hello')\n")},
+			{Path: "README.md", Content: []byte(strings.Repeat("x", 2048))},
+			{Path: "src/etl/load.py", Content: []byte("")},
+		},
+		Dependencies: []string{"pandas", "requests"},
+		CreateVenv:   true,
+	}
+	output := captureStdout(t, func() {
+		if err := writeProject(p, GenerateOptions{DryRun: true, OnConflict: ConflictFail}); err != nil {
+			t.Fatalf("writeProject() error = %v", err)
+		}
+	})
+
+	want := "Dry run: nothing will be written.\n\n" +
+		dir + "/\n" +
+		"  README.md (2.0 KB)\n" +
+		"  src/\n" +
+		"    etl/\n" +
+		"      load.py (0 B)\n" +
+		"    main.py (15 B) [conflict]\n" +
+		"\n3 files, 2.0 KB\n" +
+		"1 file(s) conflict with existing files\n" +
+		"\nDependencies:\n  - pandas\n  - requests\n" +
+		"\nA virtual environment would be initialized.\n"
+	if output != want {
+		t.Errorf("output =\n%s\nwant\n%s", output, want)
+	}
+	assertFiles(t, dir, map[string]string{"src/main.py": "print('mine')\n"})
+}
+
+// captureStdout returns what f prints to standard output
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = original }()
+
+	done := make(chan []byte)
+	go func() {
+		output, _ := io.ReadAll(r)
+		done <- output
+	}()
+	f()
+	w.Close()
+	return string(<-done)
+}
+
+func TestETLAnswersFromFlags(t *testing.T) {
+	tmpl := builtinTemplate(t, "etl-python")
+
+	tests := []struct {
+		name    string
+		file    string
+		args    []string
+		check   func(t *testing.T, answers ETLAnswers)
+		wantErr string
+	}{
+		{
+			name: "file values",
+			file: "project_name: from-file\nextract: api\nload: database\nsource:\n  url: https://example.com/items\n",
+			check: func(t *testing.T, answers ETLAnswers) {
+				if answers.ProjectName != "from-file" || answers.ExtractMethod != "api" || answers.LoadDestination != "database" {
+					t.Errorf("answers = %+v, want the values of the file", answers)
+				}
+				if answers.Source.URL != "https://example.com/items" {
+					t.Errorf("source url = %q, want the one of the file", answers.Source.URL)
+				}
+				if answers.Destination.Database.Table == "" {
+					t.Errorf("destination database has no default table")
+				}
+			},
+		},
+		{
+			name: "flags take precedence",
+			file: "project_name: from-file\nextract: api\nload: file\nvenv: true\nsource:\n  rate_limit: \"5\"\n",
+			args: []string{"--name", "from-flag", "--load", "database", "--venv=false", "--rate-limit", "2"},
+			check: func(t *testing.T, answers ETLAnswers) {
+				if answers.ProjectName != "from-flag" || answers.LoadDestination != "database" || answers.CreateVenv {
+					t.Errorf("answers = %+v, want the flag values", answers)
+				}
+				if answers.ExtractMethod != "api" {
+					t.Errorf("extract = %q, want the value of the file", answers.ExtractMethod)
+				}
+				if answers.Source.RateLimit != "2" {
+					t.Errorf("rate limit = %q, want the flag value", answers.Source.RateLimit)
+				}
+			},
+		},
+		{
+			name: "retry flags",
+			file: "project_name: p\nextract: api\nsource:\n  retry:\n    policy: fixed\n    backoff: \"5\"\n",
+			args: []string{"--max-retries", "4", "--backoff", "2.5"},
+			check: func(t *testing.T, answers ETLAnswers) {
+				want := RetryConfig{Policy: "fixed", MaxRetries: "4", Backoff: "2.5"}
+				if answers.Source.Retry != want {
+					t.Errorf("retry = %+v, want %+v", answers.Source.Retry, want)
+				}
+			},
+		},
+		{
+			name:    "unknown key",
+			file:    "project_name: p\nextract_mthod: api\n",
+			wantErr: "extract_mthod",
+		},
+		{
+			name:    "unknown nested key",
+			file:    "project_name: p\nextract: api\nsource:\n  uri: https://example.com\n",
+			wantErr: "uri",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "answers.yaml")
+			if err := os.WriteFile(path, []byte(tt.file), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			c := commandContext(t, tmpl, append([]string{"--answers", path}, tt.args...))
+			answers, err := etlAnswersFromFlags(c, tmpl)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("etlAnswersFromFlags() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("etlAnswersFromFlags() error = %v", err)
+			}
+			tt.check(t, answers)
+		})
+	}
+}
+
+func TestWizardReturnsPromptErrors(t *testing.T) {
+	tmpl := builtinTemplate(t, "etl-python")
+	chdir(t, t.TempDir())
+
+	// Answering every question with its default generates a project
+	asked := 0
+	stubPrompts(t, func(prompt survey.Prompt, response interface{}, name string) error {
+		asked++
+		return answerDefault(prompt, response, name)
+	})
+	captureStdout(t, func() {
+		if err := promptETLProjectDetails(tmpl, "", GenerateOptions{DryRun: true}); err != nil {
+			t.Fatalf("promptETLProjectDetails() error = %v", err)
+		}
+	})
+	if asked == 0 {
+		t.Fatal("the wizard asked no questions")
+	}
+
+	// Failing any one of them stops the wizard with that error
+	for failAt := 1; failAt <= asked; failAt++ {
+		n := 0
+		stubPrompts(t, func(prompt survey.Prompt, response interface{}, name string) error {
+			n++
+			if n == failAt {
+				return terminal.InterruptErr
+			}
+			return answerDefault(prompt, response, name)
+		})
+
+		var err error
+		output := captureStdout(t, func() {
+			err = promptETLProjectDetails(tmpl, "", GenerateOptions{DryRun: true})
+		})
+		if !errors.Is(err, terminal.InterruptErr) {
+			t.Errorf("question %d: error = %v, want the interrupt", failAt, err)
+		}
+		if strings.Contains(output, "Dry run") {
+			t.Errorf("question %d: the wizard went on to generate the project", failAt)
+		}
+	}
+}
+
+// chdir changes the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// builtinTemplate returns the built-in template called name
+func builtinTemplate(t *testing.T, name string) *Template {
+	t.Helper()
+
+	reg, err := Discover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, ok := reg.Lookup(name)
+	if !ok {
+		t.Fatalf("built-in template %s is missing", name)
+	}
+	return tmpl
+}
+
+// commandContext parses args with the flags of the command generating tmpl
+func commandContext(t *testing.T, tmpl *Template, args []string) *cli.Context {
+	t.Helper()
+
+	set := flag.NewFlagSet(tmpl.Command, flag.ContinueOnError)
+	set.String("name", "", "")
+	set.String("answers", "", "")
+	set.Bool("venv", false, "")
+	for _, v := range tmpl.Variables {
+		set.String(v.Name, v.Default, "")
+	}
+	for _, f := range tmpl.ConfigFlags() {
+		set.String(f.Flag, "", "")
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+// stubPrompts answers every survey question of the test with answer, which
+// is given the name of the question for those asked together
+func stubPrompts(t *testing.T, answer func(prompt survey.Prompt, response interface{}, name string) error) {
+	t.Helper()
+
+	stubAskOne(t, func(prompt survey.Prompt, response interface{}) error {
+		return answer(prompt, response, "")
+	})
+	original := askAll
+	askAll = func(questions []*survey.Question, response interface{}, _ ...survey.AskOpt) error {
+		for _, q := range questions {
+			if err := answer(q.Prompt, response, q.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	t.Cleanup(func() { askAll = original })
+}
+
+// answerDefault writes the default answer of prompt to response
+func answerDefault(prompt survey.Prompt, response interface{}, name string) error {
+	var value interface{}
+	switch p := prompt.(type) {
+	case *survey.Input:
+		value = p.Default
+	case *survey.Confirm:
+		value = p.Default
+	case *survey.Select:
+		value = p.Default
+		if value == nil {
+			value = p.Options[0]
+		}
+	case *survey.MultiSelect:
+		value = p.Default
+		if value == nil {
+			value = []string{}
+		}
+	default:
+		return fmt.Errorf("unexpected prompt %T", prompt)
+	}
+	return core.WriteAnswer(response, name, value)
+}
@@ -2,7 +2,6 @@ package templates
 
 import (
 	"fmt"
-	"os"
 	"runtime"
 	"strings"
 
@@ -10,31 +9,35 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
-// ProjectType represents different project template types
-type ProjectType string
-
-const (
-	ETLProject ProjectType = "ETL (Extract, Transform, Load)"
-	// Add more project types here as you expand
-)
-
-// InteractiveGenerator launches an interactive project generator
-func InteractiveGenerator(c *cli.Context) error {
-	fmt.Println("🚀 Welcome to the Python Project Template Generator!")
-	fmt.Println("This wizard will guide you through creating a new project.")
+// wizards holds the templates with a dedicated interactive dialog, keyed by
+// template name. Other templates are asked for their manifest variables.
+var wizards = map[string]func(t *Template, saveAnswers string, opts GenerateOptions) error{
+	"etl-python": promptETLProjectDetails,
+}
 
-	// Step 1: Choose project type
-	projectType, err := promptProjectType()
-	if err != nil {
-		return err
-	}
+// InteractiveGenerator returns an action that launches an interactive
+// project generator for the templates in reg. With --save-answers the
+// answers are also written to a file usable with the --answers flag.
+func InteractiveGenerator(reg *Registry) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		fmt.Println("🚀 Welcome to the Python Project Template Generator!")
+		fmt.Println("This wizard will guide you through creating a new project.")
+
+		// Step 1: Choose project type
+		t, err := promptProjectType(reg)
+		if err != nil {
+			return err
+		}
 
-	// Step 2: Get project details based on type
-	switch projectType {
-	case ETLProject:
-		return promptETLProjectDetails()
-	default:
-		return fmt.Errorf("unsupported project type: %s", projectType)
+		// Step 2: Get project details based on type
+		opts, err := generateOptions(c)
+		if err != nil {
+			return err
+		}
+		if wizard, ok := wizards[t.Name]; ok {
+			return wizard(t, c.String("save-answers"), opts)
+		}
+		return promptTemplateDetails(t, c.String("save-answers"), opts)
 	}
 }
 
@@ -62,28 +65,99 @@ func InteractiveGenerator(c *cli.Context) error {
 // 	return projectType, err
 // }
 
-// promptProjectType asks the user to select a project type
-func promptProjectType() (ProjectType, error) {
-	var projectTypeStr string
+// promptProjectType asks the user to select a template. Templates are
+// listed by name, since several may share a project type.
+func promptProjectType(reg *Registry) (*Template, error) {
+	var name string
+
+	var options []string
+	for _, t := range reg.Templates() {
+		options = append(options, t.Name)
+	}
 
 	prompt := &survey.Select{
 		Message: "What type of project do you want to create?",
-		Options: []string{
-			string(ETLProject),
-			// Add more project types here
-		},
+		Options: options,
 		Description: func(value string, index int) string {
-			switch value {
-			case string(ETLProject):
-				return "A data pipeline for extracting, transforming, and loading data"
-			default:
-				return ""
+			t, _ := reg.Lookup(value)
+			if t.ProjectType == "" {
+				return t.Description
 			}
+			return t.ProjectType + ": " + t.Description
 		},
 	}
 
-	err := survey.AskOne(prompt, &projectTypeStr)
-	return ProjectType(projectTypeStr), err
+	if err := askOne(prompt, &name); err != nil {
+		return nil, err
+	}
+	t, _ := reg.Lookup(name)
+	return t, nil
+}
+
+// promptTemplateDetails asks for the manifest variables of a template
+// without a dedicated dialog and generates the project
+func promptTemplateDetails(t *Template, saveAnswers string, opts GenerateOptions) error {
+	answers := ProjectAnswers{Vars: make(map[string]string)}
+	namePrompt := &survey.Input{
+		Message: "Project name:",
+		Default: t.DefaultName,
+		Help:    "The name of your project directory and package",
+	}
+	if err := askOne(namePrompt, &answers.ProjectName, survey.WithValidator(survey.Required)); err != nil {
+		return err
+	}
+
+	for _, v := range t.Variables {
+		var value string
+		var prompt survey.Prompt
+		if len(v.Options) > 0 {
+			prompt = &survey.Select{
+				Message:     v.Description + ":",
+				Options:     v.Values(),
+				Default:     v.Default,
+				Description: describeOption(t, v.Name),
+			}
+		} else {
+			prompt = &survey.Input{
+				Message: v.Description + ":",
+				Default: v.Default,
+			}
+		}
+		if err := askOne(prompt, &value); err != nil {
+			return err
+		}
+		answers.Vars[v.Name] = value
+	}
+
+	if err := promptConfigFields(t.Manifest, answers.Vars, &answers.Config); err != nil {
+		return err
+	}
+
+	venvPrompt := &survey.Confirm{
+		Message: "Initialize a virtual environment?",
+		Default: false,
+		Help:    "Creates a Python virtual environment and installs dependencies",
+	}
+	if err := askOne(venvPrompt, &answers.CreateVenv); err != nil {
+		return err
+	}
+
+	if saveAnswers != "" {
+		if err := writeAnswers(saveAnswers, answers); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("\n🔨 Generating project...")
+	if err := generateProject(t, answers, opts); err != nil {
+		return err
+	}
+	if opts.DryRun {
+		return nil
+	}
+
+	fmt.Printf("\n✅ Project successfully generated in %s/\n", answers.ProjectName)
+	return nil
 }
 
 // promptETLProjectDetails collects details for an ETL project
@@ -294,15 +368,182 @@ func promptProjectType() (ProjectType, error) {
 
 // ... existing code ...
 
+// describeOption returns the survey description of the options of the
+// named variable of t
+func describeOption(t *Template, name string) func(value string, index int) string {
+	return func(value string, index int) string {
+		v, _ := t.Variable(name)
+		c, _ := v.Component(value)
+		return c.Description
+	}
+}
+
+// stringValidator adapts a check of a text answer to a survey validator
+func stringValidator(check func(string) error) survey.Validator {
+	return func(ans interface{}) error {
+		return check(fmt.Sprint(ans))
+	}
+}
+
+// splitColumns splits a comma-separated list of column names
+func splitColumns(value string) []string {
+	var columns []string
+	for _, column := range strings.Split(value, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// promptConfigFields asks for the fields required by the manifest and by
+// the components chosen by vars that apply to answers, which must be a
+// pointer. Each answer is set before the next field is asked for, so that
+// the defaults, prompts and conditions of later fields can depend on it.
+// Fields already set in answers are offered as defaults.
+func promptConfigFields(m Manifest, vars map[string]string, answers interface{}) error {
+	for _, f := range m.RequiredFields(vars) {
+		ok, err := f.applies(answers)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		value, err := promptConfigField(f, answers)
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			continue
+		}
+		if err := setAnswerFields(answers, map[string]interface{}{f.Field: value}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// promptConfigField asks for a field of answers, and returns the value to
+// set or nil if it is left empty
+func promptConfigField(f ConfigField, answers interface{}) (interface{}, error) {
+	current, err := answerFields(answers)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := f.expand("prompt", f.Prompt, answers)
+	if err != nil {
+		return nil, err
+	}
+	if message == "" {
+		message = f.Description
+		if message == "" {
+			message = f.Field
+		}
+		message += ":"
+	}
+	help, err := f.expand("help", f.Help, answers)
+	if err != nil {
+		return nil, err
+	}
+	def := ""
+	if !f.File {
+		def = answerField(current, f.Field)
+	}
+	if def == "" {
+		if def, err = f.expand("default", f.Default, answers); err != nil {
+			return nil, err
+		}
+	}
+
+	var value string
+	switch {
+	case len(f.Options) > 0:
+		options := f.Options
+		if f.Optional && f.Default == "" {
+			options = append([]string{"None"}, options...)
+		}
+		if len(options) == 1 {
+			// Nothing to choose from
+			return options[0], nil
+		}
+		prompt := &survey.Select{Message: message, Options: options, Help: help}
+		if contains(options, def) {
+			prompt.Default = def
+		}
+		if len(f.Choices) > 0 {
+			prompt.Description = func(value string, index int) string {
+				return f.Choices[value]
+			}
+		}
+		if err := askOne(prompt, &value); err != nil {
+			return nil, err
+		}
+		if value == "None" && !contains(f.Options, value) {
+			value = ""
+		}
+
+	default:
+		var hints []string
+		if help != "" {
+			hints = append(hints, help)
+		}
+		if f.File {
+			hints = append(hints, "Path of a YAML or JSON file holding "+f.Field)
+		}
+		if f.Optional && help == "" {
+			hints = append(hints, "Leave empty to skip")
+		}
+		prompt := &survey.Input{Message: message, Default: def, Help: strings.Join(hints, ". ")}
+		check := func(value string) error {
+			if value == "" {
+				if f.Optional {
+					return nil
+				}
+				return fmt.Errorf("%s is required", f.Field)
+			}
+			if f.File {
+				if _, err := readFlagFile(f.Flag, value); err != nil {
+					return err
+				}
+			}
+			if validate, ok := fieldValidators[f.Validate]; ok {
+				return validate(value)
+			}
+			return nil
+		}
+		if err := askOne(prompt, &value, survey.WithValidator(stringValidator(check))); err != nil {
+			return nil, err
+		}
+	}
+
+	if value == "" {
+		return nil, nil
+	}
+	if !f.File {
+		return f.value(value), nil
+	}
+	return readFlagFile(f.Flag, value)
+}
+
 // promptETLProjectDetails collects details for an ETL project
-func promptETLProjectDetails() error {
+func promptETLProjectDetails(t *Template, saveAnswers string, opts GenerateOptions) error {
+	extract, _ := t.Variable("extract")
+	transform, _ := t.Variable("transform")
+	load, _ := t.Variable("load")
+	validation, _ := t.Variable("validation")
+	engine, _ := t.Variable("engine")
+	orchestrator, _ := t.Variable("orchestrator")
+
 	// Questions for ETL project
 	questions := []*survey.Question{
 		{
 			Name: "projectName",
 			Prompt: &survey.Input{
 				Message: "Project name:",
-				Default: "python-etl-project",
+				Default: t.DefaultName,
 				Help:    "The name of your project directory and package",
 			},
 			Validate: survey.Required,
@@ -310,304 +551,107 @@ func promptETLProjectDetails() error {
 		{
 			Name: "extractMethod",
 			Prompt: &survey.Select{
-				Message: "Select extraction method:",
-				Options: []string{"file", "api", "database"},
-				Default: "file",
-				Description: func(value string, index int) string {
-					switch value {
-					case "file":
-						return "Extract data from CSV, Excel, or other files"
-					case "api":
-						return "Extract data from REST APIs"
-					case "database":
-						return "Extract data from SQL databases"
-					default:
-						return ""
-					}
-				},
+				Message:     "Select extraction method:",
+				Options:     extract.Values(),
+				Default:     "file",
+				Description: describeOption(t, "extract"),
 			},
 		},
 		{
 			Name: "transformMethod",
 			Prompt: &survey.Select{
-				Message: "Select transformation method:",
-				Options: []string{"basic", "advanced"},
-				Default: "basic",
-				Description: func(value string, index int) string {
-					switch value {
-					case "basic":
-						return "Simple data cleaning and formatting"
-					case "advanced":
-						return "Advanced processing including feature engineering, scaling, etc."
-					default:
-						return ""
-					}
-				},
+				Message:     "Select transformation method:",
+				Options:     transform.Values(),
+				Default:     "basic",
+				Description: describeOption(t, "transform"),
 			},
 		},
 		{
 			Name: "loadDestination",
 			Prompt: &survey.Select{
-				Message: "Select load destination:",
-				Options: []string{"file", "database", "api"},
-				Default: "file",
-				Description: func(value string, index int) string {
-					switch value {
-					case "file":
-						return "Load data to CSV, Excel, or other files"
-					case "database":
-						return "Load data to SQL databases"
-					case "api":
-						return "Load data to REST APIs"
-					default:
-						return ""
-					}
-				},
+				Message:     "Select load destination:",
+				Options:     load.Values(),
+				Default:     "file",
+				Description: describeOption(t, "load"),
 			},
 		},
 		{
-			Name: "createVenv",
-			Prompt: &survey.Confirm{
-				Message: "Initialize a virtual environment?",
-				Default: false,
-				Help:    "Creates a Python virtual environment and installs dependencies",
+			Name: "validationMethod",
+			Prompt: &survey.Select{
+				Message:     "Select data validation:",
+				Options:     validation.Values(),
+				Default:     "none",
+				Description: describeOption(t, "validation"),
 			},
 		},
 	}
 
 	// Store answers
-	answers := struct {
-		ProjectName     string
-		ExtractMethod   string
-		TransformMethod string
-		LoadDestination string
-		CreateVenv      bool
-	}{}
+	answers := ETLAnswers{}
 
 	// Ask the questions
-	err := survey.Ask(questions, &answers)
+	err := askAll(questions, &answers)
 	if err != nil {
 		return err
 	}
 
-	// ------ADD STEP 4 HERE: Advanced Dialogs based on choices------
-
-	// Configuration details based on extract method
-	var extractConfig struct {
-		SourceType string
-		Connection map[string]string
-	}
-	extractConfig.Connection = make(map[string]string)
-
-	switch answers.ExtractMethod {
-	case "file":
-		var fileType string
-		filePrompt := &survey.Select{
-			Message: "What type of files will you extract from?",
-			Options: []string{"CSV", "Excel", "JSON", "Parquet", "Other"},
-			Default: "CSV",
-		}
-		survey.AskOne(filePrompt, &fileType)
-		extractConfig.SourceType = fileType
-
-		// Ask for file path pattern
-		var filePattern string
-		patternPrompt := &survey.Input{
-			Message: "File path or pattern to extract from:",
-			Default: "data/*.csv",
-			Help:    "Path or glob pattern for input files (e.g., data/*.csv)",
-		}
-		survey.AskOne(patternPrompt, &filePattern)
-		extractConfig.Connection["pattern"] = filePattern
-
-	case "api":
-		var apiType string
-		apiPrompt := &survey.Select{
-			Message: "What type of API?",
-			Options: []string{"REST", "GraphQL", "SOAP", "Other"},
-			Default: "REST",
-		}
-		survey.AskOne(apiPrompt, &apiType)
-		extractConfig.SourceType = apiType
-
-		// Ask for API URL
-		var apiURL string
-		urlPrompt := &survey.Input{
-			Message: "Base API URL:",
-			Default: "https://api.example.com/data",
-			Help:    "The base URL for the API you'll extract from",
-		}
-		survey.AskOne(urlPrompt, &apiURL)
-		extractConfig.Connection["url"] = apiURL
-
-		// Ask if authentication is needed
-		var needsAuth bool
-		authPrompt := &survey.Confirm{
-			Message: "Does this API require authentication?",
-			Default: true,
-		}
-		survey.AskOne(authPrompt, &needsAuth)
-
-		if needsAuth {
-			var authType string
-			authTypePrompt := &survey.Select{
-				Message: "Authentication type:",
-				Options: []string{"API Key", "OAuth2", "Basic Auth", "Bearer Token", "None"},
-				Default: "API Key",
-			}
-			survey.AskOne(authTypePrompt, &authType)
-			extractConfig.Connection["auth_type"] = authType
-		}
-
-	case "database":
-		var dbType string
-		dbPrompt := &survey.Select{
-			Message: "What database will you extract from?",
-			Options: []string{"PostgreSQL", "MySQL", "SQLite", "Oracle", "SQL Server", "Other"},
-			Default: "PostgreSQL",
-		}
-		survey.AskOne(dbPrompt, &dbType)
-		extractConfig.SourceType = dbType
-
-		// Ask for connection details if not SQLite
-		if dbType != "SQLite" {
-			var host string
-			hostPrompt := &survey.Input{
-				Message: "Database host:",
-				Default: "localhost",
-			}
-			survey.AskOne(hostPrompt, &host)
-			extractConfig.Connection["host"] = host
-
-			var port string
-			portDefault := "5432" // Default for PostgreSQL
-			if dbType == "MySQL" {
-				portDefault = "3306"
-			} else if dbType == "SQL Server" {
-				portDefault = "1433"
-			} else if dbType == "Oracle" {
-				portDefault = "1521"
-			}
-
-			portPrompt := &survey.Input{
-				Message: "Database port:",
-				Default: portDefault,
-			}
-			survey.AskOne(portPrompt, &port)
-			extractConfig.Connection["port"] = port
-
-			var dbName string
-			dbNamePrompt := &survey.Input{
-				Message: "Database name:",
-				Default: "mydatabase",
-			}
-			survey.AskOne(dbNamePrompt, &dbName)
-			extractConfig.Connection["database"] = dbName
-		} else {
-			// For SQLite, just ask for the file path
-			var dbPath string
-			pathPrompt := &survey.Input{
-				Message: "SQLite database file path:",
-				Default: "database.sqlite",
-			}
-			survey.AskOne(pathPrompt, &dbPath)
-			extractConfig.Connection["path"] = dbPath
+	// Only the engines supporting the methods chosen above are offered
+	var engines []string
+	for _, value := range engine.Values() {
+		candidate := answers
+		candidate.Engine = value
+		if validateEngine(candidate) == nil {
+			engines = append(engines, value)
 		}
 	}
 
-	// Configuration details based on load destination
-	var loadConfig struct {
-		DestType   string
-		Connection map[string]string
+	questions = []*survey.Question{
+		{
+			Name: "engine",
+			Prompt: &survey.Select{
+				Message:     "Select dataframe engine:",
+				Options:     engines,
+				Default:     "pandas",
+				Description: describeOption(t, "engine"),
+			},
+		},
+		{
+			Name: "orchestrator",
+			Prompt: &survey.Select{
+				Message:     "Select workflow orchestrator:",
+				Options:     orchestrator.Values(),
+				Default:     "none",
+				Description: describeOption(t, "orchestrator"),
+			},
+		},
+		{
+			Name: "createVenv",
+			Prompt: &survey.Confirm{
+				Message: "Initialize a virtual environment?",
+				Default: false,
+				Help:    "Creates a Python virtual environment and installs dependencies",
+			},
+		},
+	}
+	if err := askAll(questions, &answers); err != nil {
+		return err
 	}
-	loadConfig.Connection = make(map[string]string)
-
-	switch answers.LoadDestination {
-	case "file":
-		var fileType string
-		filePrompt := &survey.Select{
-			Message: "What type of files will you save to?",
-			Options: []string{"CSV", "Excel", "JSON", "Parquet", "Other"},
-			Default: "CSV",
-		}
-		survey.AskOne(filePrompt, &fileType)
-		loadConfig.DestType = fileType
-
-		// Ask for output directory
-		var outputDir string
-		dirPrompt := &survey.Input{
-			Message: "Output directory:",
-			Default: "output/",
-			Help:    "Directory where output files will be saved",
-		}
-		survey.AskOne(dirPrompt, &outputDir)
-		loadConfig.Connection["output_dir"] = outputDir
-
-	case "database":
-		// Reuse the database type selection logic from extract
-		if answers.ExtractMethod == "database" && extractConfig.SourceType != "" {
-			// Ask if using same database as extract
-			var sameDB bool
-			sameDBPrompt := &survey.Confirm{
-				Message: "Use same database connection as extract?",
-				Default: true,
-			}
-			survey.AskOne(sameDBPrompt, &sameDB)
-
-			if sameDB {
-				loadConfig.DestType = extractConfig.SourceType
-				loadConfig.Connection = extractConfig.Connection
-
-				// Just ask for the table name
-				var tableName string
-				tablePrompt := &survey.Input{
-					Message: "Output table name:",
-					Default: "etl_output",
-				}
-				survey.AskOne(tablePrompt, &tableName)
-				loadConfig.Connection["table"] = tableName
 
-				break
-			}
-		}
+	// ------ADD STEP 4 HERE: Advanced Dialogs based on choices------
 
-		// If not using same DB, ask for DB type and connection details
-		var dbType string
-		dbPrompt := &survey.Select{
-			Message: "What database will you load to?",
-			Options: []string{"PostgreSQL", "MySQL", "SQLite", "Oracle", "SQL Server", "Other"},
-			Default: "PostgreSQL",
-		}
-		survey.AskOne(dbPrompt, &dbType)
-		loadConfig.DestType = dbType
-
-		// Ask for connection details (similar to extract)
-		// ... (same code as in the extract section for database connections)
-
-	case "api":
-		var apiURL string
-		urlPrompt := &survey.Input{
-			Message: "API endpoint URL:",
-			Default: "https://api.example.com/upload",
-			Help:    "The URL where data will be sent",
-		}
-		survey.AskOne(urlPrompt, &apiURL)
-		loadConfig.Connection["url"] = apiURL
-
-		var method string
-		methodPrompt := &survey.Select{
-			Message: "HTTP method:",
-			Options: []string{"POST", "PUT", "PATCH"},
-			Default: "POST",
-		}
-		survey.AskOne(methodPrompt, &method)
-		loadConfig.Connection["method"] = method
+	// Configuration details of the chosen components, as the catalog
+	// describes them
+	if err := promptConfigFields(t.Manifest, etlVars(answers), &answers); err != nil {
+		return err
+	}
+	if err := completeETLAnswers(&answers); err != nil {
+		return err
 	}
 
 	// ------ADD STEP 5 HERE: Multiselect for Dependencies------
 
 	// Get base dependencies
-	dependencies := determineDependencies(answers.ExtractMethod, answers.TransformMethod, answers.LoadDestination)
+	dependencies := t.DependenciesFor(etlVars(answers), answers)
 
 	// Ask for additional dependencies
 	additionalDeps := []string{}
@@ -623,7 +667,6 @@ func promptETLProjectDetails() error {
 			"fastapi - Modern, fast web framework",
 			"gunicorn - WSGI HTTP Server for UNIX",
 			"dash - Interactive web-based dashboards",
-			"prefect - Workflow management system",
 			"dask - Parallel computing library",
 			"joblib - Pipeline parallelization",
 			"xlrd - Excel file reading",
@@ -637,31 +680,49 @@ func promptETLProjectDetails() error {
 		},
 		Help: "Use space to select, enter to confirm",
 	}
-	survey.AskOne(depsPrompt, &additionalDeps)
+	if err := askOne(depsPrompt, &additionalDeps); err != nil {
+		return err
+	}
 
 	// Process the selected dependencies
 	for _, dep := range additionalDeps {
 		// Extract just the package name before the dash
 		packageName := strings.Split(dep, " - ")[0]
+		answers.ExtraDependencies = append(answers.ExtraDependencies, packageName)
 		dependencies = append(dependencies, packageName)
 	}
 
 	// Show summary
 	fmt.Println("\n📋 Project Summary:")
 	fmt.Printf("  • Name: %s\n", answers.ProjectName)
-	fmt.Printf("  • Extract: %s (%s)\n", answers.ExtractMethod, extractConfig.SourceType)
+	fmt.Printf("  • Extract: %s (%s)\n", answers.ExtractMethod, answers.Source.Type)
 	fmt.Printf("  • Transform: %s\n", answers.TransformMethod)
-	fmt.Printf("  • Load: %s (%s)\n", answers.LoadDestination, loadConfig.DestType)
+	fmt.Printf("  • Load: %s (%s)\n", answers.LoadDestination, answers.Destination.Type)
+	fmt.Printf("  • Validation: %s\n", answers.ValidationMethod)
+	fmt.Printf("  • Engine: %s\n", answers.Engine)
+	fmt.Printf("  • Orchestrator: %s\n", answers.Orchestrator)
 	fmt.Printf("  • Virtual Environment: %v\n", answers.CreateVenv)
 	fmt.Printf("  • Dependencies: %d packages\n", len(dependencies))
 
+	if err := validateETLAnswers(t, answers); err != nil {
+		return err
+	}
+
+	if saveAnswers != "" {
+		if err := writeAnswers(saveAnswers, answers); err != nil {
+			return err
+		}
+	}
+
 	// Confirm generation
 	proceed := false
 	prompt := &survey.Confirm{
 		Message: "Generate this project?",
 		Default: true,
 	}
-	survey.AskOne(prompt, &proceed)
+	if err := askOne(prompt, &proceed); err != nil {
+		return err
+	}
 
 	if !proceed {
 		fmt.Println("Project generation cancelled.")
@@ -671,39 +732,12 @@ func promptETLProjectDetails() error {
 	// Generate the template
 	fmt.Println("\n🔨 Generating project...")
 
-	// Use existing template generator with the enhanced data
-	etlData := ETLTemplateData{
-		TemplateData: TemplateData{
-			ProjectName:   answers.ProjectName,
-			PackageName:   strings.ReplaceAll(strings.ToLower(answers.ProjectName), "-", "_"),
-			Description:   "A Python ETL project for data processing",
-			PythonVersion: ">=3.8",
-		},
-		ExtractMethod:   answers.ExtractMethod,
-		TransformMethod: answers.TransformMethod,
-		LoadDestination: answers.LoadDestination,
-		Dependencies:    dependencies,
-	}
-
-	// You may want to add the configurations to the template data
-	// either by extending the ETLTemplateData struct or by adding them to a map
-
-	// Create project directory
-	if err := os.MkdirAll(answers.ProjectName, 0755); err != nil {
-		return fmt.Errorf("failed to create project directory: %w", err)
-	}
-
-	// Generate the project files
-	if err := generateProjectFiles(answers.ProjectName, etlData); err != nil {
+	// Use the same generator as the etl command
+	if err := generateETLProject(t, answers, opts); err != nil {
 		return err
 	}
-
-	// Initialize virtual environment if requested
-	if answers.CreateVenv {
-		fmt.Println("\n🐍 Initializing virtual environment...")
-		if err := initializeVirtualEnv(answers.ProjectName); err != nil {
-			return err
-		}
+	if opts.DryRun {
+		return nil
 	}
 
 	fmt.Printf("\n✅ Project successfully generated in %s/\n", answers.ProjectName)
@@ -0,0 +1,194 @@
+package templates
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/core"
+)
+
+func TestPromptProjectTypeListsTemplatesByName(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"flask/template.yaml":   "name: flask-api\ncommand: flask\nproject_type: Web API\nfiles:\n  - src: a.tmpl\n    dest: a\n",
+		"flask/a.tmpl":          "a",
+		"fastapi/template.yaml": "name: fastapi-api\ncommand: fastapi\nproject_type: Web API\nfiles:\n  - src: a.tmpl\n    dest: a\n",
+		"fastapi/a.tmpl":        "a",
+	}
+	for name, content := range files {
+		if err := writeFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reg, err := Discover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var options []string
+	stubAskOne(t, func(prompt survey.Prompt, response interface{}) error {
+		options = prompt.(*survey.Select).Options
+		*response.(*string) = "flask-api"
+		return nil
+	})
+
+	chosen, err := promptProjectType(reg)
+	if err != nil {
+		t.Fatalf("promptProjectType() error = %v", err)
+	}
+	if want := []string{"etl-python", "fastapi-api", "flask-api"}; !reflect.DeepEqual(options, want) {
+		t.Errorf("options = %q, want %q", options, want)
+	}
+	if chosen.Name != "flask-api" {
+		t.Errorf("chosen template = %s, want flask-api", chosen.Name)
+	}
+}
+
+func TestPromptTemplateDetailsAsksForConfigFields(t *testing.T) {
+	dir := t.TempDir()
+	manifest := `name: service
+command: service
+requires:
+  - field: db.driver
+    description: Database driver
+    options: [postgres, mysql]
+  - field: settings
+    description: Settings
+    flag: settings
+    file: true
+    optional: true
+  - field: owner
+    optional: true
+variables:
+  - name: store
+    description: Store
+    options:
+      - name: sql
+        requires:
+          - field: store.url
+            description: Connection URL
+      - memory
+files:
+  - src: a.tmpl
+    dest: a.txt
+`
+	files := map[string]string{
+		"service/template.yaml": manifest,
+		"service/a.tmpl":        "{{ .Config.db.driver }}",
+		"settings.yaml":         "level: 3\n",
+	}
+	for name, content := range files {
+		if err := writeFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reg, err := Discover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, _ := reg.Lookup("service")
+
+	replies := map[string]string{
+		"Project name:":    "my-service",
+		"Store:":           "sql",
+		"Database driver:": "mysql",
+		"Settings:":        filepath.Join(dir, "settings.yaml"),
+		"owner:":           "",
+		"Connection URL:":  "postgresql://localhost/db",
+	}
+	stubAskOne(t, func(prompt survey.Prompt, response interface{}) error {
+		switch p := prompt.(type) {
+		case *survey.Confirm:
+			*response.(*bool) = false
+		case *survey.Select:
+			*response.(*string) = replies[p.Message]
+		case *survey.Input:
+			*response.(*string) = replies[p.Message]
+		}
+		return nil
+	})
+
+	chdir(t, dir)
+	saved := filepath.Join(dir, "answers.yaml")
+	captureStdout(t, func() {
+		if err := promptTemplateDetails(tmpl, saved, GenerateOptions{DryRun: true}); err != nil {
+			t.Fatalf("promptTemplateDetails() error = %v", err)
+		}
+	})
+
+	var answers ProjectAnswers
+	if err := readAnswers(saved, &answers); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"db":       map[string]interface{}{"driver": "mysql"},
+		"settings": map[string]interface{}{"level": 3},
+		"store":    map[string]interface{}{"url": "postgresql://localhost/db"},
+	}
+	if !reflect.DeepEqual(answers.Config, want) {
+		t.Errorf("config = %v, want %v", answers.Config, want)
+	}
+}
+
+func TestETLWizardOffersTheEnginesOfTheChosenMethods(t *testing.T) {
+	tmpl := builtinTemplate(t, "etl-python")
+	errStop := errors.New("stop")
+
+	tests := []struct {
+		name    string
+		choices map[string]string
+		want    []string
+	}{
+		{
+			name:    "files",
+			choices: map[string]string{"extractMethod": "file", "transformMethod": "basic", "loadDestination": "file", "validationMethod": "none"},
+			want:    []string{"pandas", "polars", "duckdb", "pyspark"},
+		},
+		{
+			name:    "validated files",
+			choices: map[string]string{"extractMethod": "file", "transformMethod": "basic", "loadDestination": "file", "validationMethod": "pandera"},
+			want:    []string{"pandas", "polars", "duckdb"},
+		},
+		{
+			name:    "api extract",
+			choices: map[string]string{"extractMethod": "api", "transformMethod": "basic", "loadDestination": "file", "validationMethod": "none"},
+			want:    []string{"pandas"},
+		},
+		{
+			name:    "database load",
+			choices: map[string]string{"extractMethod": "file", "transformMethod": "basic", "loadDestination": "database", "validationMethod": "none"},
+			want:    []string{"pandas"},
+		},
+		{
+			name:    "advanced transform",
+			choices: map[string]string{"extractMethod": "file", "transformMethod": "advanced", "loadDestination": "file", "validationMethod": "none"},
+			want:    []string{"pandas"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var engines []string
+			stubPrompts(t, func(prompt survey.Prompt, response interface{}, name string) error {
+				if name == "engine" {
+					engines = prompt.(*survey.Select).Options
+					return errStop
+				}
+				if choice, ok := tt.choices[name]; ok {
+					return core.WriteAnswer(response, name, choice)
+				}
+				return answerDefault(prompt, response, name)
+			})
+
+			if err := promptETLProjectDetails(tmpl, "", GenerateOptions{DryRun: true}); err != errStop {
+				t.Fatalf("promptETLProjectDetails() error = %v, want the engine question", err)
+			}
+			if !reflect.DeepEqual(engines, tt.want) {
+				t.Errorf("engines = %q, want %q", engines, tt.want)
+			}
+		})
+	}
+}
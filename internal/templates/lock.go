@@ -0,0 +1,94 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LockFile is the file inside a generated project recording how it was
+// generated, used by the update command
+const LockFile = ".pytgen.json"
+
+// Lock records the template, version and answers a project was generated
+// from, together with the generated content of every file. The content is
+// the common base of the three-way merge done by update.
+type Lock struct {
+	Template string            `json:"template"`
+	Version  string            `json:"version"`
+	Answers  json.RawMessage   `json:"answers"`
+	Files    map[string]string `json:"files"`
+}
+
+// newLock builds the lock file of a project rendered from t, recording files
+// and the bases in kept
+func newLock(t *Template, answers interface{}, files []RenderedFile, kept map[string]string) (RenderedFile, error) {
+	encoded, err := json.Marshal(answers)
+	if err != nil {
+		return RenderedFile{}, fmt.Errorf("failed to encode answers: %w", err)
+	}
+
+	lock := Lock{
+		Template: t.Name,
+		Version:  t.Version,
+		Answers:  encoded,
+		Files:    make(map[string]string, len(files)+len(kept)),
+	}
+	for path, base := range kept {
+		lock.Files[path] = base
+	}
+	for _, file := range files {
+		lock.Files[file.Path] = string(file.Content)
+	}
+
+	return encodeLock(lock)
+}
+
+// keptBases returns the bases the lock file in dir records for the rendered
+// files that were not written
+func keptBases(dir string, rendered, written []RenderedFile) map[string]string {
+	kept := make(map[string]string)
+	lock, err := readLock(dir)
+	if err != nil {
+		// A new project, or one without a readable lock, has no bases
+		return kept
+	}
+
+	isWritten := make(map[string]bool, len(written))
+	for _, file := range written {
+		isWritten[file.Path] = true
+	}
+	for _, file := range rendered {
+		if base, ok := lock.Files[file.Path]; ok && !isWritten[file.Path] {
+			kept[file.Path] = base
+		}
+	}
+	return kept
+}
+
+// encodeLock renders lock as the content of the lock file
+func encodeLock(lock Lock) (RenderedFile, error) {
+	content, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return RenderedFile{}, fmt.Errorf("failed to encode %s: %w", LockFile, err)
+	}
+	return RenderedFile{Path: LockFile, Content: append(content, '\n')}, nil
+}
+
+// readLock reads the lock file of the project in dir
+func readLock(dir string) (*Lock, error) {
+	content, err := os.ReadFile(filepath.Join(dir, LockFile))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s has no %s; was it generated by pytgen?", dir, LockFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", LockFile, err)
+	}
+
+	var lock Lock
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", LockFile, err)
+	}
+	return &lock, nil
+}
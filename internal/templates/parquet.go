@@ -0,0 +1,363 @@
+package templates
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// parquetMagic starts and ends every Parquet file
+const parquetMagic = "PAR1"
+
+// Physical types of Parquet columns
+const (
+	parquetBoolean = iota
+	parquetInt32
+	parquetInt64
+	parquetInt96
+	parquetFloat
+	parquetDouble
+	parquetByteArray
+	parquetFixedLenByteArray
+)
+
+// Converted types of Parquet columns that change how they are read
+const (
+	parquetConvertedDecimal         = 5
+	parquetConvertedDate            = 6
+	parquetConvertedTimestampMillis = 9
+	parquetConvertedTimestampMicros = 10
+)
+
+// Logical types of Parquet columns, by field of the LogicalType union
+const (
+	parquetLogicalDecimal   = 5
+	parquetLogicalDate      = 6
+	parquetLogicalTimestamp = 8
+)
+
+// parquetOptional is the repetition of columns that may hold nulls
+const parquetOptional = 1
+
+// parquetElement is the part of a SchemaElement of the Parquet file
+// metadata that decides the type of a column
+type parquetElement struct {
+	Name        string
+	Type        int64
+	Repetition  int64
+	NumChildren int64
+	Converted   int64
+	Logical     int64 // field of the LogicalType union, 0 if unset
+}
+
+// inferParquetSchema reads the columns of a Parquet file from the schema in
+// its footer. Nested columns are read as objects.
+func inferParquetSchema(path string) (*SampleSchema, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size < 12 {
+		return nil, fmt.Errorf("not a Parquet file")
+	}
+
+	// The footer ends with the length of the metadata and the magic number
+	tail := make([]byte, 8)
+	if _, err := file.ReadAt(tail, size-8); err != nil {
+		return nil, err
+	}
+	if string(tail[4:]) != parquetMagic {
+		return nil, fmt.Errorf("not a Parquet file")
+	}
+	length := int64(binary.LittleEndian.Uint32(tail[:4]))
+	if length <= 0 || length > size-12 {
+		return nil, fmt.Errorf("invalid Parquet footer")
+	}
+
+	metadata := make([]byte, length)
+	if _, err := file.ReadAt(metadata, size-8-length); err != nil {
+		return nil, err
+	}
+	elements, err := readParquetSchema(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Parquet metadata: %w", err)
+	}
+	if len(elements) < 2 {
+		return nil, fmt.Errorf("no columns")
+	}
+
+	// The first element is the root; its children are the columns
+	schema := &SampleSchema{}
+	for i := 1; i < len(elements); i = skipParquetGroup(elements, i) {
+		element := elements[i]
+		schema.Columns = append(schema.Columns, SampleColumn{
+			Name:     element.Name,
+			Type:     parquetColumnType(element),
+			Nullable: element.Repetition == parquetOptional,
+		})
+	}
+	return schema, nil
+}
+
+// skipParquetGroup returns the index of the element following element i
+// and its descendants
+func skipParquetGroup(elements []parquetElement, i int) int {
+	children := elements[i].NumChildren
+	i++
+	for ; children > 0 && i < len(elements); children-- {
+		i = skipParquetGroup(elements, i)
+	}
+	return i
+}
+
+// parquetColumnType returns the sample type of a top-level Parquet column
+func parquetColumnType(e parquetElement) string {
+	switch {
+	case e.NumChildren > 0:
+		return "object"
+	case e.Logical == parquetLogicalDate || e.Logical == parquetLogicalTimestamp,
+		e.Converted == parquetConvertedDate,
+		e.Converted == parquetConvertedTimestampMillis,
+		e.Converted == parquetConvertedTimestampMicros,
+		e.Type == parquetInt96:
+		return "datetime"
+	case e.Logical == parquetLogicalDecimal || e.Converted == parquetConvertedDecimal:
+		return "float"
+	}
+
+	switch e.Type {
+	case parquetBoolean:
+		return "bool"
+	case parquetInt32, parquetInt64:
+		return "int"
+	case parquetFloat, parquetDouble:
+		return "float"
+	}
+	return "str"
+}
+
+// Types of the Thrift compact protocol the Parquet metadata is encoded with
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftByte   = 3
+	thriftI16    = 4
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftDouble = 7
+	thriftBinary = 8
+	thriftList   = 9
+	thriftSet    = 10
+	thriftMap    = 11
+	thriftStruct = 12
+)
+
+// thriftReader decodes values of the Thrift compact protocol
+type thriftReader struct {
+	*bytes.Reader
+}
+
+// readParquetSchema decodes the schema field of the FileMetaData struct
+func readParquetSchema(metadata []byte) ([]parquetElement, error) {
+	r := thriftReader{bytes.NewReader(metadata)}
+
+	var elements []parquetElement
+	err := r.readStruct(func(id int16, typ byte) error {
+		if id != 2 || typ != thriftList {
+			return r.skip(typ)
+		}
+		size, elemType, err := r.readListHeader()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < size; i++ {
+			if elemType != thriftStruct {
+				return fmt.Errorf("unexpected schema element type %d", elemType)
+			}
+			element, err := r.readSchemaElement()
+			if err != nil {
+				return err
+			}
+			elements = append(elements, element)
+		}
+		// The rest of the metadata describes the data
+		return errSchemaRead
+	})
+	if err != nil && err != errSchemaRead {
+		return nil, err
+	}
+	return elements, nil
+}
+
+// errSchemaRead stops reading the file metadata once the schema is read
+var errSchemaRead = errors.New("schema read")
+
+// readSchemaElement decodes a SchemaElement struct
+func (r thriftReader) readSchemaElement() (parquetElement, error) {
+	e := parquetElement{Type: -1, Repetition: -1, Converted: -1}
+	err := r.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI32:
+			e.Type, err = r.readVarint()
+		case id == 3 && typ == thriftI32:
+			e.Repetition, err = r.readVarint()
+		case id == 4 && typ == thriftBinary:
+			e.Name, err = r.readString()
+		case id == 5 && typ == thriftI32:
+			e.NumChildren, err = r.readVarint()
+		case id == 6 && typ == thriftI32:
+			e.Converted, err = r.readVarint()
+		case id == 10 && typ == thriftStruct:
+			// LogicalType is a union: the id of its only field is the type
+			err = r.readStruct(func(id int16, typ byte) error {
+				e.Logical = int64(id)
+				return r.skip(typ)
+			})
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+	return e, err
+}
+
+// readStruct calls field for each field of a struct until its end
+func (r thriftReader) readStruct(field func(id int16, typ byte) error) error {
+	var id int16
+	for {
+		header, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if header == 0 {
+			return nil
+		}
+
+		typ := header & 0x0f
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			long, err := r.readVarint()
+			if err != nil {
+				return err
+			}
+			id = int16(long)
+		}
+		if err := field(id, typ); err != nil {
+			return err
+		}
+	}
+}
+
+// readVarint decodes a zigzag varint
+func (r thriftReader) readVarint() (int64, error) {
+	return binary.ReadVarint(r)
+}
+
+// readString decodes a length-prefixed string
+func (r thriftReader) readString() (string, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if length > uint64(r.Len()) {
+		return "", io.ErrUnexpectedEOF
+	}
+	buf := make([]byte, length)
+	_, err = io.ReadFull(r, buf)
+	return string(buf), err
+}
+
+// readListHeader decodes the size and element type of a list or set
+func (r thriftReader) readListHeader() (int, byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	size := int(header >> 4)
+	if size == 15 {
+		long, err := binary.ReadUvarint(r)
+		if err != nil {
+			return 0, 0, err
+		}
+		// Every element takes at least a byte
+		if long > uint64(r.Len()) {
+			return 0, 0, fmt.Errorf("list of %d elements is longer than the metadata", long)
+		}
+		size = int(long)
+	}
+	return size, header & 0x0f, nil
+}
+
+// skip reads past a value of type typ
+func (r thriftReader) skip(typ byte) error {
+	switch typ {
+	case thriftTrue, thriftFalse:
+		return nil
+	case thriftByte:
+		_, err := r.ReadByte()
+		return err
+	case thriftI16, thriftI32, thriftI64:
+		_, err := r.readVarint()
+		return err
+	case thriftDouble:
+		_, err := r.Seek(8, io.SeekCurrent)
+		return err
+	case thriftBinary:
+		_, err := r.readString()
+		return err
+	case thriftList, thriftSet:
+		size, elemType, err := r.readListHeader()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < size; i++ {
+			if err := r.skipElement(elemType); err != nil {
+				return err
+			}
+		}
+		return nil
+	case thriftMap:
+		size, err := binary.ReadUvarint(r)
+		if err != nil || size == 0 {
+			return err
+		}
+		types, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		for i := uint64(0); i < size; i++ {
+			if err := r.skipElement(types >> 4); err != nil {
+				return err
+			}
+			if err := r.skipElement(types & 0x0f); err != nil {
+				return err
+			}
+		}
+		return nil
+	case thriftStruct:
+		return r.readStruct(func(id int16, typ byte) error { return r.skip(typ) })
+	}
+	return fmt.Errorf("unknown Thrift type %d", typ)
+}
+
+// skipElement reads past an element of a collection. Booleans take a byte
+// there, unlike in struct fields.
+func (r thriftReader) skipElement(typ byte) error {
+	if typ == thriftTrue || typ == thriftFalse {
+		_, err := r.ReadByte()
+		return err
+	}
+	return r.skip(typ)
+}
@@ -0,0 +1,174 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ProjectTemplateData holds data for templates rendered from their manifest
+// variables alone
+type ProjectTemplateData struct {
+	TemplateData
+	Vars         map[string]string
+	Config       map[string]interface{}
+	Dependencies []string
+}
+
+// ProjectAnswers holds every choice needed to generate a project from its
+// manifest variables
+type ProjectAnswers struct {
+	ProjectName string            `yaml:"project_name" json:"project_name"`
+	CreateVenv  bool              `yaml:"venv" json:"venv"`
+	Vars        map[string]string `yaml:"vars" json:"vars"`
+	// Config holds the fields required by the manifest and its components,
+	// keyed as in the answers file
+	Config map[string]interface{} `yaml:"config,omitempty" json:"config,omitempty"`
+}
+
+// generators holds the templates that need more than plain variable
+// substitution, keyed by template name
+var generators = map[string]func(c *cli.Context, t *Template) error{
+	"etl-python": GenerateETLTemplate,
+}
+
+// lockRenderers re-render the templates in generators from a lock file
+var lockRenderers = map[string]func(t *Template, lock *Lock) (renderedProject, error){
+	"etl-python": renderETLFromLock,
+}
+
+// Generate generates a project from t using the flags of its command and
+// the answers file given with --answers, if any
+func Generate(c *cli.Context, t *Template) error {
+	if generate, ok := generators[t.Name]; ok {
+		return generate(c, t)
+	}
+
+	answers := ProjectAnswers{
+		ProjectName: c.String("name"),
+		CreateVenv:  c.Bool("venv"),
+		Vars:        make(map[string]string),
+	}
+	for _, v := range t.Variables {
+		answers.Vars[v.Name] = c.String(v.Name)
+	}
+
+	if path := c.String("answers"); path != "" {
+		if err := readAnswers(path, &answers); err != nil {
+			return err
+		}
+
+		// Flags set explicitly take precedence over the file
+		if c.IsSet("name") {
+			answers.ProjectName = c.String("name")
+		}
+		if c.IsSet("venv") {
+			answers.CreateVenv = c.Bool("venv")
+		}
+		for _, v := range t.Variables {
+			if c.IsSet(v.Name) {
+				answers.Vars[v.Name] = c.String(v.Name)
+			}
+		}
+	}
+
+	if err := applyConfigFlags(c, t.Manifest, answers.Vars, &answers.Config); err != nil {
+		return err
+	}
+
+	opts, err := generateOptions(c)
+	if err != nil {
+		return err
+	}
+	if err := generateProject(t, answers, opts); err != nil {
+		return err
+	}
+
+	if !opts.DryRun {
+		fmt.Printf("%s project generated successfully in %s\n", t.ProjectType, answers.ProjectName)
+	}
+	return nil
+}
+
+// validateVariables checks that every variable with options has a valid value
+func validateVariables(t *Template, vars map[string]string) error {
+	for _, v := range t.Variables {
+		if len(v.Options) > 0 && !contains(v.Values(), vars[v.Name]) {
+			return fmt.Errorf("invalid %s: %s. Valid options are: %s", v.Name, vars[v.Name], strings.Join(v.Values(), ", "))
+		}
+	}
+	return nil
+}
+
+// generateProject renders a template that has no dedicated generator
+func generateProject(t *Template, answers ProjectAnswers, opts GenerateOptions) error {
+	p, err := renderProject(t, answers)
+	if err != nil {
+		return err
+	}
+
+	return writeProject(p, opts)
+}
+
+// renderProject validates answers and renders a template that has no
+// dedicated generator in memory
+func renderProject(t *Template, answers ProjectAnswers) (renderedProject, error) {
+	projectName := answers.ProjectName
+	if projectName == "" {
+		return renderedProject{}, fmt.Errorf("project name is required")
+	}
+
+	if err := validateVariables(t, answers.Vars); err != nil {
+		return renderedProject{}, err
+	}
+	if err := checkRequirements(t.Manifest, answers.Vars, answers.Config); err != nil {
+		return renderedProject{}, err
+	}
+
+	data := ProjectTemplateData{
+		TemplateData: TemplateData{
+			ProjectName:   projectName,
+			PackageName:   packageNameFor(projectName),
+			Description:   t.Description,
+			PythonVersion: ">=3.8",
+		},
+		Vars:         answers.Vars,
+		Config:       answers.Config,
+		Dependencies: t.DependenciesFor(answers.Vars, answers.Config),
+	}
+
+	files, err := renderProjectFiles(t, answers.Vars, data)
+	if err != nil {
+		return renderedProject{}, err
+	}
+
+	return renderedProject{
+		Template:     t,
+		Answers:      answers,
+		Dir:          projectName,
+		Files:        files,
+		Dependencies: data.Dependencies,
+		CreateVenv:   answers.CreateVenv,
+	}, nil
+}
+
+// renderFromLock re-renders a project from the answers in its lock file
+func renderFromLock(t *Template, lock *Lock) (renderedProject, error) {
+	if render, ok := lockRenderers[t.Name]; ok {
+		return render(t, lock)
+	}
+
+	var answers ProjectAnswers
+	if err := json.Unmarshal(lock.Answers, &answers); err != nil {
+		return renderedProject{}, fmt.Errorf("invalid answers in %s: %w", LockFile, err)
+	}
+	return renderProject(t, answers)
+}
+
+// packageNameFor converts a project name to a Python package name
+// (lowercase, hyphens replaced with underscores)
+func packageNameFor(projectName string) string {
+	return strings.ReplaceAll(strings.ToLower(projectName), "-", "_")
+}
@@ -0,0 +1,308 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	builtin "github.com/ShmuelRob/templates-cli/templates"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile is the name of the manifest every template directory carries
+const ManifestFile = "template.yaml"
+
+// Manifest describes a project template: the command that generates it, the
+// variables it accepts and the files it renders
+type Manifest struct {
+	Name        string     `yaml:"name"`
+	Version     string     `yaml:"version"`
+	Command     string     `yaml:"command"`
+	Usage       string     `yaml:"usage"`
+	ProjectType string     `yaml:"project_type"`
+	Description string     `yaml:"description"`
+	DefaultName string     `yaml:"default_name"`
+	Variables   []Variable `yaml:"variables"`
+	Files       []FileSpec `yaml:"files"`
+
+	// Requires are the answer fields of every project, whatever components
+	// are chosen
+	Requires []ConfigField `yaml:"requires"`
+
+	// Dependencies are the Python packages every generated project needs
+	Dependencies []string `yaml:"dependencies"`
+}
+
+// Variable is a user-supplied template value, exposed as a command flag and
+// as a question in the interactive wizard
+type Variable struct {
+	Name        string      `yaml:"name"`
+	Alias       string      `yaml:"alias"`
+	Description string      `yaml:"description"`
+	Default     string      `yaml:"default"`
+	Options     []Component `yaml:"options"`
+}
+
+// FileSpec maps a template file to its destination inside the project.
+// Dest may contain template actions such as {{ .PackageName }}. When is an
+// optional template pipeline, e.g. eq .ExtractMethod "database"; the file is
+// only rendered if it evaluates to a non-empty value.
+type FileSpec struct {
+	Src  string `yaml:"src"`
+	Dest string `yaml:"dest"`
+	When string `yaml:"when"`
+}
+
+// Template is a discovered template together with the filesystem holding
+// its files. Paths in the manifest are relative to FS.
+type Template struct {
+	Manifest
+	FS     fs.FS
+	Source string // where the template was found
+}
+
+// Registry holds all known templates keyed by name
+type Registry struct {
+	templates map[string]*Template
+	warnings  []string
+}
+
+// Discover builds a registry from the built-in templates and every template
+// directory under dirs. Templates in later dirs replace earlier and built-in
+// templates with the same name. Missing dirs are not an error, and invalid
+// templates in dirs are skipped with a warning so that they can be fixed or
+// fetched anew.
+func Discover(dirs ...string) (*Registry, error) {
+	reg := &Registry{templates: make(map[string]*Template)}
+
+	builtins, errs := loadFrom(builtin.FS, "built-in templates")
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	for _, t := range builtins {
+		reg.templates[t.Name] = t
+	}
+	if err := reg.checkCommands(); err != nil {
+		return nil, err
+	}
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+
+		found, errs := loadFrom(os.DirFS(dir), dir)
+		for _, err := range errs {
+			reg.warnings = append(reg.warnings, err.Error()+"; skipped")
+		}
+		for _, t := range found {
+			if err := reg.checkCommand(t); err != nil {
+				reg.warnings = append(reg.warnings, err.Error()+"; skipped "+t.Name+" in "+dir)
+				continue
+			}
+			reg.templates[t.Name] = t
+		}
+	}
+
+	return reg, nil
+}
+
+// Warnings returns the problems found with the templates Discover skipped
+func (r *Registry) Warnings() []string {
+	return r.warnings
+}
+
+// checkCommands ensures no two templates claim the same command
+func (r *Registry) checkCommands() error {
+	commands := make(map[string]string)
+	for _, t := range r.Templates() {
+		if other, ok := commands[t.Command]; ok {
+			return fmt.Errorf("templates %s and %s both use the command %q", other, t.Name, t.Command)
+		}
+		commands[t.Command] = t.Name
+	}
+	return nil
+}
+
+// checkCommand ensures t does not claim the command of a template it does
+// not replace
+func (r *Registry) checkCommand(t *Template) error {
+	for _, other := range r.templates {
+		if other.Command == t.Command && other.Name != t.Name {
+			return fmt.Errorf("templates %s and %s both use the command %q", other.Name, t.Name, t.Command)
+		}
+	}
+	return nil
+}
+
+// loadFrom loads every directory in root that contains a manifest. The
+// templates that fail to load are returned as errors next to the others.
+func loadFrom(root fs.FS, source string) ([]*Template, []error) {
+	entries, err := fs.ReadDir(root, ".")
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to read %s: %w", source, err)}
+	}
+
+	var found []*Template
+	var errs []error
+	for _, entry := range entries {
+		// Hidden directories hold no templates, e.g. a fetch in progress
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		dir, err := fs.Sub(root, entry.Name())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to open template %s in %s: %w", entry.Name(), source, err))
+			continue
+		}
+
+		tmpl, err := loadTemplate(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			// Not a template directory
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid template %s in %s: %w", entry.Name(), source, err))
+			continue
+		}
+
+		tmpl.Source = source
+		if origin, err := fs.ReadFile(dir, SourceFile); err == nil {
+			// Fetched from a remote source
+			tmpl.Source = strings.TrimSpace(string(origin))
+		}
+		found = append(found, tmpl)
+	}
+
+	return found, errs
+}
+
+// loadTemplate reads and validates the manifest of a template directory
+func loadTemplate(dir fs.FS) (*Template, error) {
+	content, err := fs.ReadFile(dir, ManifestFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := yaml.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", ManifestFile, err)
+	}
+
+	if err := validateTemplateName(manifest.Name); err != nil {
+		return nil, fmt.Errorf("%s: %v", ManifestFile, err)
+	}
+	if manifest.Command == "" {
+		return nil, fmt.Errorf("%s: command is required", ManifestFile)
+	}
+	if len(manifest.Files) == 0 {
+		return nil, fmt.Errorf("%s: no files listed", ManifestFile)
+	}
+
+	if err := validateFileSpecs(dir, manifest.Files); err != nil {
+		return nil, err
+	}
+	for _, f := range manifest.Requires {
+		if err := validateConfigField(f); err != nil {
+			return nil, fmt.Errorf("%s: %v", ManifestFile, err)
+		}
+	}
+
+	for _, v := range manifest.Variables {
+		if v.Name == "" {
+			return nil, fmt.Errorf("%s: every variable needs a name", ManifestFile)
+		}
+		for _, c := range v.Options {
+			if err := validateComponent(dir, v, c); err != nil {
+				return nil, err
+			}
+		}
+		if len(v.Options) > 0 && v.Default != "" && !contains(v.Values(), v.Default) {
+			return nil, fmt.Errorf("%s: default %q of variable %s is not one of its options", ManifestFile, v.Default, v.Name)
+		}
+	}
+
+	if manifest.ProjectType == "" {
+		manifest.ProjectType = manifest.Name
+	}
+	if manifest.DefaultName == "" {
+		manifest.DefaultName = "python-" + manifest.Command + "-project"
+	}
+
+	return &Template{Manifest: manifest, FS: dir}, nil
+}
+
+// templateNamePattern matches template names, which name the directories
+// fetched templates are cached in
+var templateNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// validateTemplateName checks that a template name is a single clean path
+// element
+func validateTemplateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !templateNamePattern.MatchString(name) || name != filepath.Base(name) || name == "." || name == ".." {
+		return fmt.Errorf("invalid name %q. Use letters, digits, dots, dashes and underscores", name)
+	}
+	return nil
+}
+
+// validateFileSpecs checks that the files listed in a manifest exist and
+// that their destinations and conditions parse
+func validateFileSpecs(dir fs.FS, files []FileSpec) error {
+	for _, file := range files {
+		if file.Src == "" || file.Dest == "" {
+			return fmt.Errorf("%s: every file needs src and dest", ManifestFile)
+		}
+		if _, err := fs.Stat(dir, file.Src); err != nil {
+			return fmt.Errorf("%s: template file %s not found", ManifestFile, file.Src)
+		}
+		if _, err := template.New("dest").Parse(file.Dest); err != nil {
+			return fmt.Errorf("%s: invalid dest for %s: %v", ManifestFile, file.Src, err)
+		}
+		if _, err := parseCondition(file.When); err != nil {
+			return fmt.Errorf("%s: invalid condition for %s: %v", ManifestFile, file.Src, err)
+		}
+	}
+	return nil
+}
+
+// Templates returns all registered templates sorted by name
+func (r *Registry) Templates() []*Template {
+	list := make([]*Template, 0, len(r.templates))
+	for _, t := range r.templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Lookup returns the template with the given name
+func (r *Registry) Lookup(name string) (*Template, bool) {
+	t, ok := r.templates[name]
+	return t, ok
+}
+
+// Find returns the template with the given name or command
+func (r *Registry) Find(nameOrCommand string) (*Template, bool) {
+	if t, ok := r.templates[nameOrCommand]; ok {
+		return t, true
+	}
+	for _, t := range r.templates {
+		if t.Command == nameOrCommand {
+			return t, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,44 @@
+package templates
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverSkipsBrokenTemplates(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"good/template.yaml":   "name: good\ncommand: good\nfiles:\n  - src: a.tmpl\n    dest: a\n",
+		"good/a.tmpl":          "a",
+		"broken/template.yaml": "name: [unterminated\n",
+		"clash/template.yaml":  "name: clash\ncommand: etl\nfiles:\n  - src: a.tmpl\n    dest: a\n",
+		"clash/a.tmpl":         "a",
+	}
+	for name, content := range files {
+		if err := writeFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reg, err := Discover(dir, filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if _, ok := reg.Lookup("good"); !ok {
+		t.Errorf("valid template good was not discovered")
+	}
+	if _, ok := reg.Lookup("clash"); ok {
+		t.Errorf("template clash claiming a built-in command was discovered")
+	}
+	if _, ok := reg.Lookup("etl-python"); !ok {
+		t.Errorf("built-in template etl-python is missing")
+	}
+
+	warnings := strings.Join(reg.Warnings(), "\n")
+	for _, want := range []string{"broken", "clash"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("warnings %q do not mention %s", warnings, want)
+		}
+	}
+}
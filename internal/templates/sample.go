@@ -0,0 +1,575 @@
+package templates
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SampleSchema is the schema of a sample dataset and a few of its rows,
+// inferred at generation
+type SampleSchema struct {
+	Path    string         `yaml:"path" json:"path"`
+	Format  string         `yaml:"format" json:"format"` // CSV, JSON, JSON Lines or Parquet
+	Columns []SampleColumn `yaml:"columns" json:"columns"`
+	Rows    [][]string     `yaml:"rows,omitempty" json:"rows,omitempty"` // values as text, empty when missing
+}
+
+// SampleColumn is a column of a sample dataset
+type SampleColumn struct {
+	Name     string `yaml:"name" json:"name"`
+	Field    string `yaml:"field" json:"field"` // Python identifier of the column
+	Type     string `yaml:"type" json:"type"`   // int, float, bool, datetime, str or object
+	Nullable bool   `yaml:"nullable,omitempty" json:"nullable,omitempty"`
+}
+
+// sampleRows is the number of rows of a sample kept as test fixtures
+const sampleRows = 5
+
+// inferRows is the number of rows of a sample whose values decide the types
+// of its columns
+const inferRows = 1000
+
+// sampleFormats maps the extensions of sample files to their format
+var sampleFormats = map[string]string{
+	".csv":     "CSV",
+	".tsv":     "CSV",
+	".txt":     "CSV",
+	".json":    "JSON",
+	".jsonl":   "JSON Lines",
+	".ndjson":  "JSON Lines",
+	".parquet": "Parquet",
+}
+
+// sampleTypes are the types a column of a sample can have
+var sampleTypes = []string{"int", "float", "bool", "datetime", "str", "object"}
+
+// dateLayouts are the layouts of the text read as dates and times
+var dateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	time.RFC3339,
+	time.RFC3339Nano,
+}
+
+// boolValues are the text read as booleans, in lower case
+var boolValues = []string{"true", "false", "yes", "no"}
+
+// pythonKeywords are the names a Python identifier cannot take
+var pythonKeywords = []string{
+	"False", "None", "True", "and", "as", "assert", "async", "await", "break", "class",
+	"continue", "def", "del", "elif", "else", "except", "finally", "for", "from", "global",
+	"if", "import", "in", "is", "lambda", "nonlocal", "not", "or", "pass", "raise",
+	"return", "try", "while", "with", "yield",
+}
+
+// nonIdentifierPattern matches runs of characters Python identifiers cannot hold
+var nonIdentifierPattern = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// InferSchema reads the columns and first rows of a CSV, JSON, JSON Lines
+// or Parquet sample. Parquet files only give their columns.
+func InferSchema(path string) (*SampleSchema, error) {
+	format, ok := sampleFormats[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sample file %s. Use a CSV, JSON, JSON Lines or Parquet file", path)
+	}
+
+	var schema *SampleSchema
+	var err error
+	switch format {
+	case "CSV":
+		schema, err = inferCSVSchema(path)
+	case "Parquet":
+		schema, err = inferParquetSchema(path)
+	default:
+		schema, err = inferJSONSchema(path, format == "JSON Lines")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sample %s: %w", path, err)
+	}
+
+	schema.Path = filepath.ToSlash(path)
+	schema.Format = format
+	if err := schema.setFields(); err != nil {
+		return nil, fmt.Errorf("sample %s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// inferCSVSchema reads the header and first rows of a CSV file, guessing
+// its delimiter from the header
+func inferCSVSchema(path string) (*SampleSchema, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buffered := bufio.NewReader(file)
+	header, err := buffered.Peek(4096)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	firstLine, _, _ := bytes.Cut(header, []byte("\n"))
+
+	reader := csv.NewReader(buffered)
+	reader.Comma = csvDelimiter(string(firstLine))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	names, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("no header: %w", err)
+	}
+	names[0] = strings.TrimPrefix(names[0], "\ufeff") // byte order mark
+
+	var rows [][]string
+	for len(rows) < inferRows {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make([]string, len(names))
+		copy(row, record)
+		rows = append(rows, row)
+	}
+
+	return schemaFromText(names, rows), nil
+}
+
+// csvDelimiter returns the most frequent of the usual delimiters in a CSV
+// header line
+func csvDelimiter(line string) rune {
+	delimiter, most := ',', 0
+	for _, candidate := range []rune{',', ';', '\t', '|'} {
+		if n := strings.Count(line, string(candidate)); n > most {
+			delimiter, most = candidate, n
+		}
+	}
+	return delimiter
+}
+
+// schemaFromText builds the schema of rows of text values, inferring the
+// type of each column from its values
+func schemaFromText(names []string, rows [][]string) *SampleSchema {
+	schema := &SampleSchema{}
+	for i, name := range names {
+		column := SampleColumn{Name: strings.TrimSpace(name)}
+		var values []string
+		for _, row := range rows {
+			if value := strings.TrimSpace(row[i]); value != "" {
+				values = append(values, value)
+			} else {
+				column.Nullable = true
+			}
+		}
+		column.Type = inferTextType(values)
+		schema.Columns = append(schema.Columns, column)
+	}
+	schema.Rows = firstRows(rows)
+	return schema
+}
+
+// inferTextType returns the narrowest type of a column holding values
+func inferTextType(values []string) string {
+	if len(values) == 0 {
+		return "str"
+	}
+	for _, typ := range []string{"int", "float", "bool", "datetime"} {
+		matches := true
+		for _, value := range values {
+			if !isTextOfType(value, typ) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return typ
+		}
+	}
+	return "str"
+}
+
+// isTextOfType reports whether value reads as a value of typ
+func isTextOfType(value, typ string) bool {
+	switch typ {
+	case "int":
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case "float":
+		// Words such as nan or inf are left to text columns
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil && strings.ContainsAny(value, "0123456789")
+	case "bool":
+		return contains(boolValues, strings.ToLower(value))
+	case "datetime":
+		for _, layout := range dateLayouts {
+			if _, err := time.Parse(layout, value); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inferJSONSchema reads the first records of a JSON array of objects or of
+// a JSON Lines file. Columns are ordered by first appearance.
+func inferJSONSchema(path string, lines bool) (*SampleSchema, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if !lines {
+		if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+			return nil, fmt.Errorf("expected a JSON array of records")
+		}
+	}
+
+	var names []string
+	index := map[string]int{}
+	var records []map[string]interface{}
+	for len(records) < inferRows && decoder.More() {
+		keys, record, err := decodeRecord(decoder)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if _, ok := index[key]; !ok {
+				index[key] = len(names)
+				names = append(names, key)
+			}
+		}
+		records = append(records, record)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no records")
+	}
+
+	schema := &SampleSchema{}
+	for _, name := range names {
+		schema.Columns = append(schema.Columns, inferJSONColumn(name, records))
+	}
+	for _, record := range firstRecords(records) {
+		row := make([]string, len(names))
+		for i, name := range names {
+			row[i] = jsonText(record[name])
+		}
+		schema.Rows = append(schema.Rows, row)
+	}
+	return schema, nil
+}
+
+// decodeRecord decodes the next JSON object of decoder, returning its keys
+// in order
+func decodeRecord(decoder *json.Decoder) ([]string, map[string]interface{}, error) {
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, nil, fmt.Errorf("expected a JSON object for each record")
+	}
+
+	var keys []string
+	record := map[string]interface{}{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := token.(string)
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+		record[key] = value
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, nil, err
+	}
+	return keys, record, nil
+}
+
+// inferJSONColumn infers the type of a column of JSON records. Strings are
+// read as dates and times when they all parse as such.
+func inferJSONColumn(name string, records []map[string]interface{}) SampleColumn {
+	column := SampleColumn{Name: name}
+	kinds := map[string]bool{}
+	var texts []string
+	for _, record := range records {
+		switch value := record[name].(type) {
+		case nil:
+			column.Nullable = true
+		case bool:
+			kinds["bool"] = true
+		case json.Number:
+			if _, err := value.Int64(); err == nil {
+				kinds["int"] = true
+			} else {
+				kinds["float"] = true
+			}
+		case string:
+			kinds["str"] = true
+			texts = append(texts, value)
+		default:
+			kinds["object"] = true
+		}
+	}
+
+	switch {
+	case len(kinds) == 0:
+		column.Type = "str"
+	case len(kinds) == 2 && kinds["int"] && kinds["float"]:
+		column.Type = "float"
+	case len(kinds) > 1:
+		column.Type = "object"
+	case kinds["str"]:
+		if inferTextType(texts) == "datetime" {
+			column.Type = "datetime"
+		} else {
+			column.Type = "str"
+		}
+	default:
+		for kind := range kinds {
+			column.Type = kind
+		}
+	}
+	return column
+}
+
+// jsonText renders a decoded JSON value as sample text
+func jsonText(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case json.Number:
+		return value.String()
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		encoded, _ := json.Marshal(value)
+		return string(encoded)
+	}
+}
+
+// firstRows returns the rows kept as test fixtures
+func firstRows(rows [][]string) [][]string {
+	if len(rows) > sampleRows {
+		return rows[:sampleRows]
+	}
+	return rows
+}
+
+// firstRecords returns the records kept as test fixtures
+func firstRecords(records []map[string]interface{}) []map[string]interface{} {
+	if len(records) > sampleRows {
+		return records[:sampleRows]
+	}
+	return records
+}
+
+// setFields checks the column names of s and gives each column a distinct
+// Python identifier
+func (s *SampleSchema) setFields() error {
+	if len(s.Columns) == 0 {
+		return fmt.Errorf("no columns")
+	}
+
+	names := map[string]bool{}
+	fields := map[string]bool{}
+	for i := range s.Columns {
+		column := &s.Columns[i]
+		if column.Name == "" {
+			return fmt.Errorf("column %d has no name", i+1)
+		}
+		if names[column.Name] {
+			return fmt.Errorf("column %q appears more than once", column.Name)
+		}
+		names[column.Name] = true
+
+		field := fieldName(column.Name)
+		for n := 2; fields[field]; n++ {
+			field = fmt.Sprintf("%s_%d", fieldName(column.Name), n)
+		}
+		fields[field] = true
+		column.Field = field
+	}
+	return nil
+}
+
+// fieldName turns a column name into a Python identifier
+func fieldName(name string) string {
+	field := strings.Trim(nonIdentifierPattern.ReplaceAllString(name, "_"), "_")
+	if field == "" {
+		field = "column"
+	}
+	if field[0] >= '0' && field[0] <= '9' {
+		field = "column_" + field
+	}
+	if contains(pythonKeywords, field) {
+		field += "_"
+	}
+	return field
+}
+
+// validateSchema checks a schema given in an answers file
+func validateSchema(s *SampleSchema) error {
+	if len(s.Columns) == 0 {
+		return fmt.Errorf("schema needs at least one column")
+	}
+	for _, column := range s.Columns {
+		if !contains(sampleTypes, column.Type) {
+			return fmt.Errorf("invalid type %q of schema column %q. Use one of: %s", column.Type, column.Name, strings.Join(sampleTypes, ", "))
+		}
+		if !namePattern.MatchString(column.Field) || contains(pythonKeywords, column.Field) {
+			return fmt.Errorf("invalid field %q of schema column %q. Use a Python identifier", column.Field, column.Name)
+		}
+	}
+	for i, row := range s.Rows {
+		if len(row) != len(s.Columns) {
+			return fmt.Errorf("schema row %d has %d values for %d columns", i+1, len(row), len(s.Columns))
+		}
+	}
+	return nil
+}
+
+// PythonType returns the annotation of the field of a column
+func (c SampleColumn) PythonType() string {
+	typ := map[string]string{
+		"int": "int", "float": "float", "bool": "bool", "datetime": "datetime", "str": "str", "object": "Any",
+	}[c.Type]
+	if c.Nullable && typ != "Any" {
+		return "Optional[" + typ + "]"
+	}
+	return typ
+}
+
+// Literal renders a sample value of a column as a Python literal, None when
+// missing
+func (c SampleColumn) Literal(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "None"
+	}
+	switch c.Type {
+	case "int", "float":
+		return strings.TrimSpace(value)
+	case "bool":
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "true", "yes":
+			return "True"
+		case "false", "no":
+			return "False"
+		}
+	case "object":
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(value), &decoded); err == nil {
+			return pythonValue(decoded)
+		}
+	}
+	return strconv.Quote(value)
+}
+
+// pythonValue renders a decoded JSON value as a Python literal
+func pythonValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "None"
+	case bool:
+		if value {
+			return "True"
+		}
+		return "False"
+	case float64:
+		return formatNumber(value)
+	case string:
+		return strconv.Quote(value)
+	case []interface{}:
+		var items []string
+		for _, item := range value {
+			items = append(items, pythonValue(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var items []string
+		for _, key := range keys {
+			items = append(items, strconv.Quote(key)+": "+pythonValue(value[key]))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return "None"
+}
+
+// FixtureRows returns the rows of the sample as Python dictionaries keyed
+// by column. Samples without rows, such as Parquet files, get two rows made
+// up from the types of their columns.
+func (s SampleSchema) FixtureRows() []string {
+	rows := s.Rows
+	if len(rows) == 0 {
+		rows = s.madeUpRows()
+	}
+
+	var literals []string
+	for _, row := range rows {
+		var items []string
+		for i, column := range s.Columns {
+			items = append(items, strconv.Quote(column.Name)+": "+column.Literal(row[i]))
+		}
+		literals = append(literals, "{"+strings.Join(items, ", ")+"}")
+	}
+	return literals
+}
+
+// madeUpValues are the values of the rows made up for each column type
+var madeUpValues = map[string][2]string{
+	"int":      {"1", "2"},
+	"float":    {"1.5", "2.5"},
+	"bool":     {"true", "false"},
+	"datetime": {"2024-01-01", "2024-01-02"},
+	"str":      {"a", "b"},
+	"object":   {`{"key": "a"}`, `{"key": "b"}`},
+}
+
+// madeUpRows returns two rows of values of the types of the columns of s
+func (s SampleSchema) madeUpRows() [][]string {
+	rows := make([][]string, 2)
+	for _, column := range s.Columns {
+		for i := range rows {
+			rows[i] = append(rows[i], madeUpValues[column.Type][i])
+		}
+	}
+	return rows
+}
+
+// HasType reports whether a column of s has type typ
+func (s SampleSchema) HasType(typ string) bool {
+	for _, column := range s.Columns {
+		if column.Type == typ {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInferParquetSchema(t *testing.T) {
+	schema, err := InferSchema(filepath.Join("testdata", "columns.parquet"))
+	if err != nil {
+		t.Fatalf("InferSchema() error = %v", err)
+	}
+
+	want := []SampleColumn{
+		{Name: "id", Field: "id", Type: "int"},
+		{Name: "name", Field: "name", Type: "str", Nullable: true},
+		{Name: "score", Field: "score", Type: "float", Nullable: true},
+		{Name: "active", Field: "active", Type: "bool", Nullable: true},
+		{Name: "created at", Field: "created_at", Type: "datetime", Nullable: true},
+		{Name: "birth_date", Field: "birth_date", Type: "datetime", Nullable: true},
+		{Name: "amount", Field: "amount", Type: "float"},
+		{Name: "address", Field: "address", Type: "object", Nullable: true},
+		{Name: "legacy", Field: "legacy", Type: "datetime", Nullable: true},
+	}
+	if !reflect.DeepEqual(schema.Columns, want) {
+		t.Errorf("columns = %+v\nwant %+v", schema.Columns, want)
+	}
+	if schema.Format != "Parquet" || len(schema.Rows) != 0 {
+		t.Errorf("format = %s with %d rows, want Parquet without rows", schema.Format, len(schema.Rows))
+	}
+}
+
+func TestInferParquetSchemaRejectsBrokenFiles(t *testing.T) {
+	tests := []struct {
+		file    string
+		wantErr string
+	}{
+		{file: "truncated_footer.parquet", wantErr: "invalid Parquet footer"},
+		{file: "truncated_metadata.parquet", wantErr: "invalid Parquet metadata"},
+		{file: "bogus_list_size.parquet", wantErr: "longer than the metadata"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			_, err := InferSchema(filepath.Join("testdata", tt.file))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("InferSchema() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("not parquet", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.parquet")
+		if err := os.WriteFile(path, []byte("id,name\n1,a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := InferSchema(path); err == nil || !strings.Contains(err.Error(), "not a Parquet file") {
+			t.Errorf("InferSchema() error = %v, want not a Parquet file", err)
+		}
+	})
+}
+
+func TestInferTextType(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   string
+	}{
+		{name: "no values", values: nil, want: "str"},
+		{name: "ints", values: []string{"1", "-20", "300"}, want: "int"},
+		{name: "ints and floats", values: []string{"1", "2.5", "1e3"}, want: "float"},
+		{name: "nan and inf", values: []string{"nan", "inf"}, want: "str"},
+		{name: "bools", values: []string{"true", "No", "YES", "False"}, want: "bool"},
+		{name: "0 and 1", values: []string{"0", "1"}, want: "int"},
+		{name: "dates", values: []string{"2024-01-31", "2024-02-29"}, want: "datetime"},
+		{
+			name: "mixed ISO 8601 layouts",
+			values: []string{
+				"2024-01-31",
+				"2024-01-31 08:30:00",
+				"2024-01-31T08:30:00",
+				"2024-01-31T08:30:00.123456",
+				"2024-01-31T08:30:00Z",
+				"2024-01-31T08:30:00+02:00",
+				"2024-01-31T08:30:00.5-05:00",
+			},
+			want: "datetime",
+		},
+		{name: "invalid date", values: []string{"2024-01-31", "2024-02-30"}, want: "str"},
+		{name: "other date layout", values: []string{"31/01/2024"}, want: "str"},
+		{name: "mixed types", values: []string{"1", "a"}, want: "str"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inferTextType(tt.values); got != tt.want {
+				t.Errorf("inferTextType(%q) = %s, want %s", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVDelimiter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   rune
+	}{
+		{header: "id,name,value", want: ','},
+		{header: "id;name;value", want: ';'},
+		{header: "id\tname\tvalue", want: '\t'},
+		{header: "id|name|value", want: '|'},
+		{header: "id;name,with comma;value", want: ';'},
+		{header: "id", want: ','},
+		{header: "", want: ','},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := csvDelimiter(tt.header); got != tt.want {
+				t.Errorf("csvDelimiter(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "id", want: "id"},
+		{name: "First Name", want: "First_Name"},
+		{name: "  price (USD)  ", want: "price_USD"},
+		{name: "e-mail", want: "e_mail"},
+		{name: "2024", want: "column_2024"},
+		{name: "1st place", want: "column_1st_place"},
+		{name: "class", want: "class_"},
+		{name: "None", want: "None_"},
+		{name: "none", want: "none"},
+		{name: "%", want: "column"},
+		{name: "_private_", want: "private"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fieldName(tt.name); got != tt.want {
+				t.Errorf("fieldName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		want    []string
+		wantErr string
+	}{
+		{name: "distinct", columns: []string{"id", "name"}, want: []string{"id", "name"}},
+		{
+			name:    "same field",
+			columns: []string{"first name", "first-name", "first_name"},
+			want:    []string{"first_name", "first_name_2", "first_name_3"},
+		},
+		{
+			name:    "numbered name taken",
+			columns: []string{"a b", "a_2", "a-b"},
+			want:    []string{"a_b", "a_2", "a_b_2"},
+		},
+		{name: "keywords", columns: []string{"class", "class_"}, want: []string{"class_", "class__2"}},
+		{name: "duplicate name", columns: []string{"id", "id"}, wantErr: "appears more than once"},
+		{name: "empty name", columns: []string{"id", ""}, wantErr: "column 2 has no name"},
+		{name: "no columns", wantErr: "no columns"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := &SampleSchema{}
+			for _, name := range tt.columns {
+				schema.Columns = append(schema.Columns, SampleColumn{Name: name, Type: "str"})
+			}
+
+			err := schema.setFields()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("setFields() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("setFields() error = %v", err)
+			}
+			var got []string
+			for _, column := range schema.Columns {
+				got = append(got, column.Field)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fields = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInferCSVSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.csv")
+	content := "id;name;joined;active\n1;Ada;2024-01-31;yes\n2;;2024-02-01T09:00:00Z;no\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := InferSchema(path)
+	if err != nil {
+		t.Fatalf("InferSchema() error = %v", err)
+	}
+	want := []SampleColumn{
+		{Name: "id", Field: "id", Type: "int"},
+		{Name: "name", Field: "name", Type: "str", Nullable: true},
+		{Name: "joined", Field: "joined", Type: "datetime"},
+		{Name: "active", Field: "active", Type: "bool"},
+	}
+	if !reflect.DeepEqual(schema.Columns, want) {
+		t.Errorf("columns = %+v\nwant %+v", schema.Columns, want)
+	}
+	if len(schema.Rows) != 2 {
+		t.Errorf("rows = %q, want the 2 rows of the sample", schema.Rows)
+	}
+}
@@ -0,0 +1,392 @@
+package templates
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// SourceFile records, inside a fetched template, the source it came from
+const SourceFile = ".pytgen-source"
+
+// archiveExtensions are the archive formats a template can be fetched from
+var archiveExtensions = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// RemoteSource is a template kept outside the local template directories,
+// either in a git repository or in an archive
+type RemoteSource struct {
+	Kind   string // "git" or "archive"
+	URL    string // repository URL or archive path
+	Subdir string // template directory inside the repository or archive
+	Ref    string // git branch, tag or commit
+}
+
+// ParseSource parses a template source such as
+// git+file:///path/repo//etl-python@v1.2 or templates.tar.gz//etl-python
+func ParseSource(spec string) (RemoteSource, error) {
+	var src RemoteSource
+
+	if strings.HasPrefix(spec, "git+") {
+		src.Kind = "git"
+		src.URL = strings.TrimPrefix(spec, "git+")
+
+		// A ref follows the last @ after the last slash, so user@host is kept
+		if at := strings.LastIndex(src.URL, "@"); at > strings.LastIndex(src.URL, "/") {
+			src.URL, src.Ref = src.URL[:at], src.URL[at+1:]
+			if src.Ref == "" {
+				return src, fmt.Errorf("invalid template source %q: empty ref after @", spec)
+			}
+			// git would read a ref starting with - as an option
+			if strings.HasPrefix(src.Ref, "-") {
+				return src, fmt.Errorf("invalid template source %q: bad ref %q", spec, src.Ref)
+			}
+		}
+	} else {
+		src.Kind = "archive"
+		src.URL = spec
+	}
+
+	// The subdirectory follows a double slash after the scheme
+	start := strings.Index(src.URL, "://") + 3
+	if start < 3 {
+		start = 1
+	}
+	if start < len(src.URL) {
+		if i := strings.Index(src.URL[start:], "//"); i >= 0 {
+			src.URL, src.Subdir = src.URL[:start+i], strings.Trim(src.URL[start+i+2:], "/")
+			if !fs.ValidPath(src.Subdir) {
+				return src, fmt.Errorf("invalid template source %q: bad subdirectory %q", spec, src.Subdir)
+			}
+		}
+	}
+
+	if src.Kind == "archive" && archiveExtension(src.URL) == "" {
+		return src, fmt.Errorf("unsupported template source %q: expected git+URL or a %s archive", spec, strings.Join(archiveExtensions, ", "))
+	}
+	if src.URL == "" {
+		return src, fmt.Errorf("invalid template source %q", spec)
+	}
+
+	return src, nil
+}
+
+// archiveExtension returns the archive extension of name, if any
+func archiveExtension(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// AddTemplate returns the action of the add command, which fetches a template
+// from a remote source into the cache so it gets its own command
+func AddTemplate(reg *Registry, cacheDir string) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			return fmt.Errorf("expected exactly one template source")
+		}
+
+		t, err := FetchTemplate(c.Args().First(), cacheDir, reg)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Added template %s %s from %s\n", t.Name, t.Version, t.Source)
+		fmt.Printf("Generate it with: pytgen %s\n", t.Command)
+		return nil
+	}
+}
+
+// FetchTemplate fetches the template at spec and installs it in cacheDir,
+// replacing an earlier fetch of the same template
+func FetchTemplate(spec, cacheDir string, reg *Registry) (*Template, error) {
+	src, err := ParseSource(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.MkdirTemp("", "pytgen-fetch-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	checkout := filepath.Join(tmp, "src")
+	switch src.Kind {
+	case "git":
+		err = cloneRepository(src, checkout)
+	default:
+		err = extractArchive(src.URL, checkout)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := templateRoot(checkout, src.Subdir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", spec, err)
+	}
+
+	t, err := loadTemplate(os.DirFS(root))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template in %s: %w", spec, err)
+	}
+
+	// A fetched template must not take the command of a different template
+	for _, other := range reg.Templates() {
+		if other.Command == t.Command && other.Name != t.Name {
+			return nil, fmt.Errorf("template %s uses the command %q, which already belongs to %s", t.Name, t.Command, other.Name)
+		}
+	}
+
+	dest, err := cachePath(cacheDir, t.Name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template in %s: %w", spec, err)
+	}
+	if err := installTemplate(root, dest, spec); err != nil {
+		return nil, err
+	}
+
+	t.FS = os.DirFS(dest)
+	t.Source = spec
+	return t, nil
+}
+
+// cachePath returns the directory template name is installed in, which must
+// be a direct child of cacheDir
+func cachePath(cacheDir, name string) (string, error) {
+	if err := validateTemplateName(name); err != nil {
+		return "", err
+	}
+	dest := filepath.Join(cacheDir, name)
+	rel, err := filepath.Rel(cacheDir, dest)
+	if err != nil || rel != name {
+		return "", fmt.Errorf("template name %q is outside the template cache", name)
+	}
+	return dest, nil
+}
+
+// cloneRepository clones the repository of src into dir and checks out its ref
+func cloneRepository(src RemoteSource, dir string) error {
+	if err := runGit("clone", "--quiet", "--", src.URL, dir); err != nil {
+		return fmt.Errorf("failed to clone %s: %w", src.URL, err)
+	}
+	if src.Ref != "" {
+		if strings.HasPrefix(src.Ref, "-") {
+			return fmt.Errorf("invalid ref %q", src.Ref)
+		}
+		if err := runGit("-C", dir, "checkout", "--quiet", src.Ref); err != nil {
+			return fmt.Errorf("failed to check out %s: %w", src.Ref, err)
+		}
+	}
+	return nil
+}
+
+// runGit runs git, returning its output as the error on failure
+func runGit(args ...string) error {
+	output, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// extractArchive extracts the archive at name into dir
+func extractArchive(name, dir string) error {
+	if archiveExtension(name) == ".zip" {
+		return extractZip(name, dir)
+	}
+	return extractTar(name, dir)
+}
+
+// extractTar extracts a tar archive, gzip compressed or not, into dir
+func extractTar(name, dir string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if archiveExtension(name) != ".tar" {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		// Only regular files are extracted; directories are created as needed
+		switch header.Typeflag {
+		case tar.TypeReg:
+		case tar.TypeSymlink, tar.TypeLink:
+			return fmt.Errorf("archive entry %q is a link, which templates cannot hold", header.Name)
+		default:
+			continue
+		}
+		if err := extractFile(dir, header.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// extractZip extracts a zip archive into dir
+func extractZip(name, dir string) error {
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		if file.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("archive entry %q is a link, which templates cannot hold", file.Name)
+		}
+		if !file.Mode().IsRegular() {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %s from %s: %w", file.Name, name, err)
+		}
+		err = extractFile(dir, file.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractFile writes one archive member below dir, rejecting names that
+// would escape it
+func extractFile(dir, name string, r io.Reader) error {
+	clean := path.Clean(strings.TrimPrefix(filepath.ToSlash(name), "./"))
+	if !fs.ValidPath(clean) || clean == "." {
+		return fmt.Errorf("archive entry %q is outside the archive", name)
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return writeFile(filepath.Join(dir, filepath.FromSlash(clean)), content)
+}
+
+// templateRoot locates the template directory in a checkout: subdir if
+// given, otherwise the checkout itself or its only top-level directory
+func templateRoot(checkout, subdir string) (string, error) {
+	if subdir != "" {
+		root := filepath.Join(checkout, filepath.FromSlash(subdir))
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			return "", fmt.Errorf("directory %s not found", subdir)
+		}
+		return root, nil
+	}
+
+	if _, err := os.Stat(filepath.Join(checkout, ManifestFile)); err == nil {
+		return checkout, nil
+	}
+
+	// Archives commonly wrap their content in a single directory
+	entries, err := os.ReadDir(checkout)
+	if err != nil {
+		return "", fmt.Errorf("failed to read source: %w", err)
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != ".git" {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	if len(dirs) == 1 {
+		root := filepath.Join(checkout, dirs[0])
+		if _, err := os.Stat(filepath.Join(root, ManifestFile)); err == nil {
+			return root, nil
+		}
+	}
+
+	return "", fmt.Errorf("no %s found; name the template directory with //DIR", ManifestFile)
+}
+
+// installTemplate copies the template in root to dest, recording spec as its
+// source. The copy is built next to dest and renamed into place.
+func installTemplate(root, dest, spec string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create template cache: %w", err)
+	}
+
+	staging, err := os.MkdirTemp(filepath.Dir(dest), "."+filepath.Base(dest)+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create template cache: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return writeFile(filepath.Join(staging, rel), content)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy template: %w", err)
+	}
+
+	if err := writeFile(filepath.Join(staging, SourceFile), []byte(spec+"\n")); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dest); err != nil {
+		return fmt.Errorf("failed to replace cached template: %w", err)
+	}
+	if err := os.Rename(staging, dest); err != nil {
+		return fmt.Errorf("failed to install template: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package templates
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSource(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    RemoteSource
+		wantErr string
+	}{
+		{
+			spec: "git+https://example.com/org/repo.git",
+			want: RemoteSource{Kind: "git", URL: "https://example.com/org/repo.git"},
+		},
+		{
+			spec: "git+https://example.com/org/repo.git//etl-python",
+			want: RemoteSource{Kind: "git", URL: "https://example.com/org/repo.git", Subdir: "etl-python"},
+		},
+		{
+			spec: "git+https://example.com/org/repo.git//templates/etl-python@v1.2",
+			want: RemoteSource{Kind: "git", URL: "https://example.com/org/repo.git", Subdir: "templates/etl-python", Ref: "v1.2"},
+		},
+		{
+			spec: "git+file:///srv/repo//etl-python@main",
+			want: RemoteSource{Kind: "git", URL: "file:///srv/repo", Subdir: "etl-python", Ref: "main"},
+		},
+		{
+			// The @ of user@host is not a ref
+			spec: "git+ssh://git@example.com/org/repo.git",
+			want: RemoteSource{Kind: "git", URL: "ssh://git@example.com/org/repo.git"},
+		},
+		{
+			spec: "git+ssh://git@example.com/org/repo.git@abc123",
+			want: RemoteSource{Kind: "git", URL: "ssh://git@example.com/org/repo.git", Ref: "abc123"},
+		},
+		{
+			spec: "templates.tar.gz",
+			want: RemoteSource{Kind: "archive", URL: "templates.tar.gz"},
+		},
+		{
+			spec: "dist/templates.zip//etl-python",
+			want: RemoteSource{Kind: "archive", URL: "dist/templates.zip", Subdir: "etl-python"},
+		},
+		{
+			spec: "/abs/templates.tgz//etl-python/",
+			want: RemoteSource{Kind: "archive", URL: "/abs/templates.tgz", Subdir: "etl-python"},
+		},
+		{spec: "git+https://example.com/repo.git@", wantErr: "empty ref"},
+		{spec: "git+https://example.com/repo.git@--upload-pack=touch", wantErr: "bad ref"},
+		{spec: "git+https://example.com/repo.git//../etc", wantErr: "bad subdirectory"},
+		{spec: "templates.rar", wantErr: "unsupported template source"},
+		{spec: "git+", wantErr: "invalid template source"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseSource(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseSource(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSource(%q) error = %v", tt.spec, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSource(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+// archiveEntry is a member of a test archive
+type archiveEntry struct {
+	name    string
+	content string
+	link    string // target of a symbolic link
+}
+
+// writeTar writes entries to a gzip compressed tar archive in dir
+func writeTar(t *testing.T, dir string, entries []archiveEntry) string {
+	t.Helper()
+	name := filepath.Join(dir, "template.tar.gz")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		if e.link != "" {
+			header = &tar.Header{Name: e.name, Mode: 0777, Linkname: e.link, Typeflag: tar.TypeSymlink}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if e.link == "" {
+			if _, err := tw.Write([]byte(e.content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+// writeZip writes entries to a zip archive in dir
+func writeZip(t *testing.T, dir string, entries []archiveEntry) string {
+	t.Helper()
+	name := filepath.Join(dir, "template.zip")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		content := e.content
+		if e.link != "" {
+			header.SetMode(os.ModeSymlink | 0777)
+			content = e.link
+		} else {
+			header.SetMode(0644)
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestExtractArchive(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []archiveEntry
+		wantErr string
+	}{
+		{
+			name: "regular files",
+			entries: []archiveEntry{
+				{name: "tmpl/template.yaml", content: "name: t\n"},
+				{name: "./tmpl/src/main.py.tmpl", content: "print()\n"},
+			},
+		},
+		{
+			name:    "parent directory",
+			entries: []archiveEntry{{name: "../evil.txt", content: "x"}},
+			wantErr: "outside the archive",
+		},
+		{
+			name:    "nested parent directory",
+			entries: []archiveEntry{{name: "tmpl/../../evil.txt", content: "x"}},
+			wantErr: "outside the archive",
+		},
+		{
+			name:    "absolute path",
+			entries: []archiveEntry{{name: "/tmp/evil.txt", content: "x"}},
+			wantErr: "outside the archive",
+		},
+		{
+			name:    "symbolic link",
+			entries: []archiveEntry{{name: "tmpl/link", link: "/etc/passwd"}},
+			wantErr: "is a link",
+		},
+	}
+
+	writers := map[string]func(*testing.T, string, []archiveEntry) string{"tar": writeTar, "zip": writeZip}
+	for kind, write := range writers {
+		for _, tt := range tests {
+			t.Run(kind+"/"+tt.name, func(t *testing.T) {
+				tmp := t.TempDir()
+				archive := write(t, tmp, tt.entries)
+				dest := filepath.Join(tmp, "out")
+
+				err := extractArchive(archive, dest)
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("extractArchive() error = %v, want %q", err, tt.wantErr)
+					}
+					if _, err := os.Stat(filepath.Join(tmp, "evil.txt")); err == nil {
+						t.Errorf("entry was written outside the destination")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("extractArchive() error = %v", err)
+				}
+				for _, e := range tt.entries {
+					content, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(strings.TrimPrefix(e.name, "./"))))
+					if err != nil {
+						t.Fatalf("entry %s not extracted: %v", e.name, err)
+					}
+					if string(content) != e.content {
+						t.Errorf("entry %s = %q, want %q", e.name, content, e.content)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFetchTemplateRejectsBadNames(t *testing.T) {
+	reg, err := Discover()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"..", ".", "../..", "a/b", "-flag", ".hidden"} {
+		t.Run(name, func(t *testing.T) {
+			tmp := t.TempDir()
+			cacheDir := filepath.Join(tmp, "config", "cache")
+			sibling := filepath.Join(tmp, "config", "keep.txt")
+			if err := writeFile(sibling, []byte("keep")); err != nil {
+				t.Fatal(err)
+			}
+
+			manifest := "name: " + quoteYAML(name) + "\ncommand: evil\nfiles:\n  - src: a.tmpl\n    dest: a\n"
+			archive := writeTar(t, tmp, []archiveEntry{
+				{name: "tmpl/template.yaml", content: manifest},
+				{name: "tmpl/a.tmpl", content: "a"},
+			})
+
+			if _, err := FetchTemplate(archive, cacheDir, reg); err == nil || !strings.Contains(err.Error(), "invalid name") {
+				t.Fatalf("FetchTemplate() error = %v, want an invalid name", err)
+			}
+			if _, err := os.Stat(sibling); err != nil {
+				t.Errorf("file next to the cache was removed: %v", err)
+			}
+		})
+	}
+}
+
+func TestCachePath(t *testing.T) {
+	cacheDir := filepath.Join("home", "cache")
+	if dest, err := cachePath(cacheDir, "etl-python"); err != nil || dest != filepath.Join(cacheDir, "etl-python") {
+		t.Errorf("cachePath(etl-python) = %q, %v", dest, err)
+	}
+	for _, name := range []string{"", "..", "../x", "x/..", "/x"} {
+		if _, err := cachePath(cacheDir, name); err == nil {
+			t.Errorf("cachePath(%q) accepted a name outside the cache", name)
+		}
+	}
+}
+
+// quoteYAML renders s as a double-quoted YAML string
+func quoteYAML(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,209 @@
+package templates
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sqlIndent indents the lines of a query to sit in the body of a generated
+// Python function
+const sqlIndent = "        "
+
+// sqlCastTypes are the DuckDB types of the cast types. DuckDB enums need
+// their values up front, so categories stay text.
+var sqlCastTypes = map[string]string{
+	"int": "BIGINT", "float": "DOUBLE", "str": "VARCHAR", "datetime": "TIMESTAMP", "category": "VARCHAR",
+}
+
+// sqlAggregates are the DuckDB aggregates of the functions of a metric, with
+// %s standing for the column
+var sqlAggregates = map[string]string{
+	"sum": "sum(%s)", "mean": "avg(%s)", "median": "median(%s)", "min": "min(%s)", "max": "max(%s)",
+	"count": "count(%s)", "nunique": "count(DISTINCT %s)",
+	"first": "first(%[1]s ORDER BY __order) FILTER (WHERE %[1]s IS NOT NULL)",
+	"last":  "last(%[1]s ORDER BY __order) FILTER (WHERE %[1]s IS NOT NULL)",
+}
+
+// sqlJoins are the DuckDB joins of the join types
+var sqlJoins = map[string]string{"inner": "JOIN", "left": "LEFT JOIN", "right": "RIGHT JOIN", "outer": "FULL JOIN"}
+
+// sqlNumbered numbers the rows of data in the order they are read, for steps
+// that depend on it
+const sqlNumbered = "WITH numbered AS (SELECT *, row_number() OVER () AS __order FROM data)"
+
+// SQL returns the DuckDB query of a step on the table data, indented to sit
+// in the body of its function. Join steps read the joined table as joined.
+func (s TransformStep) SQL() string {
+	var lines []string
+	switch s.Type {
+	case "rename":
+		var renames []string
+		for _, column := range sortedKeys(s.Columns) {
+			renames = append(renames, sqlName(column)+" AS "+sqlName(s.Columns[column]))
+		}
+		lines = []string{"SELECT * RENAME (" + strings.Join(renames, ", ") + ") FROM data"}
+	case "cast":
+		lines = []string{"SELECT * REPLACE ("}
+		for _, column := range sortedKeys(s.Columns) {
+			lines = append(lines, "    "+sqlCast(column, s.Columns[column])+" AS "+sqlName(column)+",")
+		}
+		lines = append(lines, ") FROM data")
+	case "filter":
+		lines = []string{"SELECT * FROM data WHERE " + s.sqlCondition()}
+	case "dedupe":
+		order := "__order"
+		if s.Keep == "last" {
+			order = "__order DESC"
+		}
+		if len(s.Keys) > 0 {
+			lines = []string{
+				sqlNumbered,
+				"SELECT * EXCLUDE (__order) FROM numbered",
+				"QUALIFY row_number() OVER (PARTITION BY " + sqlNames(s.Keys) + " ORDER BY " + order + ") = 1",
+				"ORDER BY __order",
+			}
+		} else {
+			// Identical rows are grouped, keeping the position of the kept one
+			position := "min(__order)"
+			if s.Keep == "last" {
+				position = "max(__order)"
+			}
+			lines = []string{
+				sqlNumbered,
+				"SELECT * EXCLUDE (__order) FROM (",
+				"    SELECT * EXCLUDE (__order), " + position + " AS __order FROM numbered GROUP BY ALL",
+				") ORDER BY __order",
+			}
+		}
+	case "fill":
+		ordered := false
+		for _, strategy := range s.Columns {
+			ordered = ordered || strategy == "ffill" || strategy == "bfill"
+		}
+		from := "data"
+		if ordered {
+			lines = []string{sqlNumbered, "SELECT * EXCLUDE (__order) REPLACE ("}
+			from = "numbered"
+		} else {
+			lines = []string{"SELECT * REPLACE ("}
+		}
+		for _, c := range s.FillCases() {
+			name := sqlName(c.Column)
+			fill := ""
+			switch s.Columns[c.Column] {
+			case "mean":
+				fill = "avg(" + name + ") OVER ()"
+			case "median":
+				fill = "median(" + name + ") OVER ()"
+			case "mode":
+				// The most frequent value, the smallest of those tied
+				fill = "(SELECT " + name + " FROM data WHERE " + name + " IS NOT NULL GROUP BY " + name + " ORDER BY count(*) DESC, " + name + " LIMIT 1)"
+			case "zero":
+				fill = "0"
+			case "ffill":
+				fill = "last_value(" + name + " IGNORE NULLS) OVER (ORDER BY __order ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW)"
+			case "bfill":
+				fill = "first_value(" + name + " IGNORE NULLS) OVER (ORDER BY __order ROWS BETWEEN CURRENT ROW AND UNBOUNDED FOLLOWING)"
+			default:
+				fill = sqlLiteral(s.Values[c.Column])
+			}
+			lines = append(lines, "    coalesce("+name+", "+fill+") AS "+name+",")
+		}
+		lines = append(lines, ") FROM "+from)
+		if ordered {
+			lines = append(lines, "ORDER BY __order")
+		}
+	case "derive":
+		lines = []string{"SELECT *, " + s.Expr + " AS " + sqlName(s.Column) + " FROM data"}
+	case "join":
+		lines = []string{"SELECT * FROM data " + sqlJoins[s.How] + " joined USING (" + sqlNames(s.Keys) + ")"}
+	case "aggregate":
+		from := "data"
+		for _, m := range s.AggregateMetrics() {
+			if m.Func == "first" || m.Func == "last" {
+				lines = []string{sqlNumbered}
+				from = "numbered"
+			}
+		}
+		lines = append(lines, "SELECT")
+		for _, key := range s.Keys {
+			lines = append(lines, "    "+sqlName(key)+",")
+		}
+		for _, m := range s.AggregateMetrics() {
+			lines = append(lines, "    "+fmt.Sprintf(sqlAggregates[m.Func], sqlName(m.Column))+" AS "+sqlName(m.Name)+",")
+		}
+		lines = append(lines,
+			"FROM "+from,
+			"GROUP BY "+sqlNames(s.Keys),
+			"ORDER BY "+sqlNames(s.Keys),
+		)
+	}
+	return sqlIndent + strings.Join(lines, "\n"+sqlIndent)
+}
+
+// sqlCondition returns the condition of a filter step. Missing values are
+// kept by != and not in, as they are by pandas.
+func (s TransformStep) sqlCondition() string {
+	column := sqlName(s.Column)
+	switch s.Op {
+	case "notnull":
+		return column + " IS NOT NULL"
+	case "isnull":
+		return column + " IS NULL"
+	case "in", "not in":
+		var items []string
+		for _, item := range strings.Split(s.Value, ",") {
+			items = append(items, sqlLiteral(strings.TrimSpace(item)))
+		}
+		if s.Op == "in" {
+			return column + " IN (" + strings.Join(items, ", ") + ")"
+		}
+		return column + " IS NULL OR " + column + " NOT IN (" + strings.Join(items, ", ") + ")"
+	case "!=":
+		return column + " IS DISTINCT FROM " + sqlLiteral(s.Value)
+	case "==":
+		return column + " = " + sqlLiteral(s.Value)
+	}
+	return column + " " + s.Op + " " + sqlLiteral(s.Value)
+}
+
+// sqlCast returns the expression converting a column to a cast type.
+// Booleans are read from text such as yes/no or 1/0.
+func sqlCast(column, typ string) string {
+	name := sqlName(column)
+	if typ == "bool" {
+		text := "lower(trim(CAST(" + name + " AS VARCHAR)))"
+		return "CASE WHEN " + text + " IN ('true', 't', 'yes', 'y', '1') THEN TRUE" +
+			" WHEN " + text + " IN ('false', 'f', 'no', 'n', '0') THEN FALSE END"
+	}
+	return "CAST(" + name + " AS " + sqlCastTypes[typ] + ")"
+}
+
+// sqlName quotes a column name
+func sqlName(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqlNames quotes column names and separates them with commas
+func sqlNames(names []string) string {
+	var quoted []string
+	for _, name := range names {
+		quoted = append(quoted, sqlName(name))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// sqlLiteral renders a spec value as a SQL number, boolean or string
+func sqlLiteral(value string) string {
+	switch literal := pythonLiteral(value); literal {
+	case "True":
+		return "TRUE"
+	case "False":
+		return "FALSE"
+	default:
+		if !strings.HasPrefix(literal, `"`) {
+			return literal
+		}
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,167 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// stagingPrefix starts the name of the hidden directory files are staged in
+const stagingPrefix = ".pytgen-staging-"
+
+// stagedProject writes project files into a staging directory and moves them
+// into place only once all of them were written, so a failure never leaves a
+// half-populated project behind
+type stagedProject struct {
+	dir     string // final project directory
+	staging string // hidden directory holding the staged files
+	created bool   // dir did not exist and was created by commit
+
+	// Set by commit when merging into an existing directory
+	moved       []string          // committed paths, relative to dir
+	backups     map[string]string // committed path -> backup of the replaced file
+	createdDirs []string          // directories created inside dir
+}
+
+// stageProject writes files into a new staging directory for dir. An
+// existing project is staged inside itself, so that nothing outside it is
+// touched; a new one is staged next to where it will be created.
+func stageProject(dir string, files []RenderedFile) (*stagedProject, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project directory: %w", err)
+	}
+
+	// Staging beside the files keeps the final renames on one filesystem
+	parent := abs
+	if _, err := os.Stat(abs); os.IsNotExist(err) {
+		parent = filepath.Dir(abs)
+		if err := os.MkdirAll(parent, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory %s: %w", parent, err)
+		}
+	}
+
+	staging, err := os.MkdirTemp(parent, stagingPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	s := &stagedProject{dir: abs, staging: staging, backups: make(map[string]string)}
+	for _, file := range files {
+		if err := writeFile(filepath.Join(staging, "files", filepath.FromSlash(file.Path)), file.Content); err != nil {
+			s.cleanup()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// commit moves the staged files into the project directory, leaving the
+// staging directory inside it alone. If it fails, the project directory is
+// left as it was.
+func (s *stagedProject) commit(files []RenderedFile) error {
+	staged := filepath.Join(s.staging, "files")
+
+	if _, err := os.Stat(s.dir); os.IsNotExist(err) {
+		if err := os.MkdirAll(staged, 0755); err != nil {
+			return fmt.Errorf("failed to create project directory: %w", err)
+		}
+		if err := os.Rename(staged, s.dir); err != nil {
+			return fmt.Errorf("failed to create project directory: %w", err)
+		}
+		s.created = true
+		return nil
+	}
+
+	for _, file := range files {
+		if err := s.move(file.Path); err != nil {
+			s.rollback()
+			return err
+		}
+	}
+
+	return nil
+}
+
+// move moves one staged file into place, backing up the file it replaces
+func (s *stagedProject) move(path string) error {
+	src := filepath.Join(s.staging, "files", filepath.FromSlash(path))
+	dest := filepath.Join(s.dir, filepath.FromSlash(path))
+
+	if err := s.mkdirAll(filepath.Dir(dest)); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(dest); err == nil {
+		backup := filepath.Join(s.staging, "backup", filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(backup), 0755); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", dest, err)
+		}
+		if err := os.Rename(dest, backup); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", dest, err)
+		}
+		s.backups[path] = backup
+	}
+
+	if err := os.Rename(src, dest); err != nil {
+		if backup, ok := s.backups[path]; ok {
+			os.Rename(backup, dest)
+			delete(s.backups, path)
+		}
+		return fmt.Errorf("failed to create file %s: %w", dest, err)
+	}
+
+	s.moved = append(s.moved, path)
+	return nil
+}
+
+// mkdirAll creates dir and records the directories it had to create
+func (s *stagedProject) mkdirAll(dir string) error {
+	var missing []string
+	for d := dir; d != s.dir; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = append(missing, d)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	s.createdDirs = append(s.createdDirs, missing...)
+	return nil
+}
+
+// rollback undoes a commit: a created project directory is removed, and in
+// an existing one the committed files are removed or restored from backup
+func (s *stagedProject) rollback() {
+	if s.created {
+		os.RemoveAll(s.dir)
+		s.created = false
+		return
+	}
+
+	for i := len(s.moved) - 1; i >= 0; i-- {
+		path := s.moved[i]
+		dest := filepath.Join(s.dir, filepath.FromSlash(path))
+		os.Remove(dest)
+		if backup, ok := s.backups[path]; ok {
+			os.Rename(backup, dest)
+		}
+	}
+	s.moved = nil
+
+	// Remove created directories deepest first; non-empty ones stay
+	sort.Slice(s.createdDirs, func(i, j int) bool { return len(s.createdDirs[i]) > len(s.createdDirs[j]) })
+	for _, dir := range s.createdDirs {
+		os.Remove(dir)
+	}
+	s.createdDirs = nil
+}
+
+// cleanup removes the staging directory and any backups in it
+func (s *stagedProject) cleanup() {
+	os.RemoveAll(s.staging)
+}
@@ -0,0 +1,164 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStageProjectStagesInsideExistingProjects(t *testing.T) {
+	tests := []struct {
+		name   string
+		exists bool
+	}{
+		{name: "existing project", exists: true},
+		{name: "new project", exists: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := t.TempDir()
+			dir := filepath.Join(parent, "project")
+			if tt.exists {
+				if err := os.Mkdir(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			files := []RenderedFile{{Path: "a.txt", Content: []byte("a\n")}}
+			staged, err := stageProject(dir, files)
+			if err != nil {
+				t.Fatalf("stageProject() error = %v", err)
+			}
+			defer staged.cleanup()
+
+			want := parent
+			if tt.exists {
+				want = dir
+			}
+			if got := filepath.Dir(staged.staging); got != want {
+				t.Errorf("staged in %s, want %s", got, want)
+			}
+			if !strings.HasPrefix(filepath.Base(staged.staging), stagingPrefix) {
+				t.Errorf("staging directory %s is not hidden", staged.staging)
+			}
+
+			if err := staged.commit(files); err != nil {
+				t.Fatalf("commit() error = %v", err)
+			}
+			staged.cleanup()
+			assertFiles(t, parent, map[string]string{"project/a.txt": "a\n"})
+		})
+	}
+}
+
+func TestStagedProjectCommitAndRollback(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  map[string]string // files in the project before the commit
+		files     []RenderedFile
+		wantErr   string
+		committed map[string]string // files after the commit
+	}{
+		{
+			name:      "new project",
+			files:     []RenderedFile{{Path: "a.txt", Content: []byte("a\n")}, {Path: "src/b.py", Content: []byte("b\n")}},
+			committed: map[string]string{"a.txt": "a\n", "src/b.py": "b\n"},
+		},
+		{
+			name:      "existing project",
+			existing:  map[string]string{"a.txt": "old\n", "notes.txt": "mine\n"},
+			files:     []RenderedFile{{Path: "a.txt", Content: []byte("a\n")}, {Path: "src/b.py", Content: []byte("b\n")}},
+			committed: map[string]string{"a.txt": "a\n", "notes.txt": "mine\n", "src/b.py": "b\n"},
+		},
+		{
+			name:     "failed commit",
+			existing: map[string]string{"a.txt": "old\n", "x": "a file, not a directory\n"},
+			files:    []RenderedFile{{Path: "a.txt", Content: []byte("a\n")}, {Path: "new/c.txt", Content: []byte("c\n")}, {Path: "x/b.txt", Content: []byte("b\n")}},
+			wantErr:  "failed to create directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "project")
+			for path, content := range tt.existing {
+				if err := writeFile(filepath.Join(dir, filepath.FromSlash(path)), []byte(content)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			staged, err := stageProject(dir, tt.files)
+			if err != nil {
+				t.Fatalf("stageProject() error = %v", err)
+			}
+			defer staged.cleanup()
+
+			err = staged.commit(tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("commit() error = %v, want %q", err, tt.wantErr)
+				}
+				assertFiles(t, dir, tt.existing)
+				return
+			}
+			if err != nil {
+				t.Fatalf("commit() error = %v", err)
+			}
+			assertFiles(t, dir, tt.committed)
+
+			staged.rollback()
+			if tt.existing == nil {
+				if _, err := os.Stat(dir); !os.IsNotExist(err) {
+					t.Errorf("rollback left the created project directory behind")
+				}
+				return
+			}
+			assertFiles(t, dir, tt.existing)
+		})
+	}
+}
+
+// assertFiles checks that the regular files under dir, outside of staging
+// directories, are exactly want, keyed by slash-separated path
+func assertFiles(t *testing.T, dir string, want map[string]string) {
+	t.Helper()
+
+	got := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if strings.HasPrefix(info.Name(), stagingPrefix) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		got[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, content := range want {
+		if got[path] != content {
+			t.Errorf("%s = %q, want %q", path, got[path], content)
+		}
+	}
+	for path := range got {
+		if _, ok := want[path]; !ok {
+			t.Errorf("unexpected file %s", path)
+		}
+	}
+}
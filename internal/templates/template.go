@@ -1,9 +1,11 @@
 package templates
 
 import (
+    "bytes"
     "fmt"
-    "os"
-    "path/filepath"
+    "io/fs"
+    "path"
+    "strings"
     "text/template"
 )
 
@@ -16,31 +18,85 @@ type TemplateData struct {
     // Add more common fields as needed
 }
 
-// RenderTemplate renders a template with given data to the specified path
-func RenderTemplate(tmplPath, destPath string, data interface{}) error {
-    // Ensure the directory exists
-    dir := filepath.Dir(destPath)
-    if err := os.MkdirAll(dir, 0755); err != nil {
-        return fmt.Errorf("failed to create directory %s: %w", dir, err)
+// RenderTemplate renders a template from fsys with given data to the specified path.
+// tmplPath is a slash-separated path relative to the root of fsys.
+func RenderTemplate(fsys fs.FS, tmplPath, destPath string, data interface{}) error {
+    content, err := renderTemplate(fsys, tmplPath, data)
+    if err != nil {
+        return err
     }
 
+    return writeFile(destPath, content)
+}
+
+// renderTemplate renders a template from fsys with given data in memory
+func renderTemplate(fsys fs.FS, tmplPath string, data interface{}) ([]byte, error) {
     // Read template content
-    tmpl, err := template.ParseFiles(tmplPath)
+    tmpl, err := template.ParseFS(fsys, tmplPath)
     if err != nil {
-        return fmt.Errorf("failed to parse template %s: %w", tmplPath, err)
+        return nil, fmt.Errorf("failed to parse template %s: %w", tmplPath, err)
     }
 
-    // Create destination file
-    file, err := os.Create(destPath)
-    if err != nil {
-        return fmt.Errorf("failed to create file %s: %w", destPath, err)
+    // Execute template
+    var buf bytes.Buffer
+    if err := tmpl.Execute(&buf, data); err != nil {
+        return nil, fmt.Errorf("failed to execute template %s: %w", tmplPath, err)
     }
-    defer file.Close()
 
-    // Execute template
-    if err := tmpl.Execute(file, data); err != nil {
-        return fmt.Errorf("failed to execute template %s: %w", tmplPath, err)
+    return buf.Bytes(), nil
+}
+
+// parseCondition parses a FileSpec condition. An empty condition is nil and
+// always holds.
+func parseCondition(when string) (*template.Template, error) {
+    if strings.TrimSpace(when) == "" {
+        return nil, nil
     }
+    return template.New("when").Parse("{{ if " + when + " }}true{{ end }}")
+}
+
+// planFiles evaluates the conditions and destination paths of the files in
+// the manifest of t and of the components chosen by vars, returning the files
+// to render with their resolved slash-separated destinations
+func planFiles(t *Template, vars map[string]string, data interface{}) ([]FileSpec, error) {
+    var files []FileSpec
+    seen := make(map[string]string)
+
+    for _, file := range t.FilesFor(vars) {
+        cond, err := parseCondition(file.When)
+        if err != nil {
+            return nil, fmt.Errorf("invalid condition for %s: %w", file.Src, err)
+        }
+        if cond != nil {
+            var out strings.Builder
+            if err := cond.Execute(&out, data); err != nil {
+                return nil, fmt.Errorf("failed to evaluate condition for %s: %w", file.Src, err)
+            }
+            if out.Len() == 0 {
+                continue
+            }
+        }
+
+        destTmpl, err := template.New("dest").Parse(file.Dest)
+        if err != nil {
+            return nil, fmt.Errorf("invalid dest for %s: %w", file.Src, err)
+        }
+        var dest strings.Builder
+        if err := destTmpl.Execute(&dest, data); err != nil {
+            return nil, fmt.Errorf("failed to resolve dest for %s: %w", file.Src, err)
+        }
 
-    return nil
-}
\ No newline at end of file
+        resolved := path.Clean(dest.String())
+        if resolved == "." || resolved == ".." || path.IsAbs(resolved) || strings.HasPrefix(resolved, "../") {
+            return nil, fmt.Errorf("dest %q of %s is outside the project", dest.String(), file.Src)
+        }
+        if other, ok := seen[resolved]; ok {
+            return nil, fmt.Errorf("templates %s and %s both render to %s", other, file.Src, resolved)
+        }
+        seen[resolved] = file.Src
+
+        files = append(files, FileSpec{Src: file.Src, Dest: resolved})
+    }
+
+    return files, nil
+}
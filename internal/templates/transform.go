@@ -0,0 +1,495 @@
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TransformStep is a step of a transformation spec. Which settings apply
+// depends on its type.
+type TransformStep struct {
+	Type    string            `yaml:"type" json:"type"`
+	Name    string            `yaml:"name,omitempty" json:"name,omitempty"`       // name of the generated function
+	Columns map[string]string `yaml:"columns,omitempty" json:"columns,omitempty"` // rename: old to new name; cast: type; fill: strategy
+	Values  map[string]string `yaml:"values,omitempty" json:"values,omitempty"`   // fill: literal value
+	Column  string            `yaml:"column,omitempty" json:"column,omitempty"`   // filter: compared column; derive: new column
+	Op      string            `yaml:"op,omitempty" json:"op,omitempty"`           // filter: comparison
+	Value   string            `yaml:"value,omitempty" json:"value,omitempty"`     // filter: compared value, comma-separated for in
+	Expr    string            `yaml:"expr,omitempty" json:"expr,omitempty"`       // derive: pandas expression, SQL with other engines
+	Keys    []string          `yaml:"keys,omitempty" json:"keys,omitempty"`       // dedupe, join and aggregate keys
+	Keep    string            `yaml:"keep,omitempty" json:"keep,omitempty"`       // dedupe: first or last
+	Path    string            `yaml:"path,omitempty" json:"path,omitempty"`       // join: file of the joined table
+	How     string            `yaml:"how,omitempty" json:"how,omitempty"`         // join: inner, left, right or outer
+	Metrics map[string]string `yaml:"metrics,omitempty" json:"metrics,omitempty"` // aggregate: output column to func(column)
+}
+
+// transformStepTypes are the step types of a transformation spec
+var transformStepTypes = []string{"rename", "cast", "filter", "dedupe", "fill", "derive", "join", "aggregate"}
+
+// castTypes are the types a cast step converts columns to
+var castTypes = []string{"int", "float", "str", "bool", "datetime", "category"}
+
+// filterOps are the comparisons of a filter step
+var filterOps = []string{"==", "!=", ">", ">=", "<", "<=", "in", "not in", "notnull", "isnull"}
+
+// fillStrategies are the computed values a fill step replaces missing values with
+var fillStrategies = []string{"mean", "median", "mode", "zero", "ffill", "bfill"}
+
+// joinTypes are the ways a join step combines tables
+var joinTypes = []string{"inner", "left", "right", "outer"}
+
+// aggregateFuncs are the functions a metric of an aggregate step applies
+var aggregateFuncs = []string{"sum", "mean", "median", "min", "max", "count", "nunique", "first", "last"}
+
+// metricPattern matches a metric of an aggregate step, e.g. sum(amount)
+var metricPattern = regexp.MustCompile(`^\s*([a-z]+)\(\s*([^()]+?)\s*\)\s*$`)
+
+// exprStringPattern matches the string literals of a derive expression
+var exprStringPattern = regexp.MustCompile(`'[^']*'|"[^"]*"`)
+
+// exprNamePattern matches the names in a derive expression, and columns
+// quoted in backticks
+var exprNamePattern = regexp.MustCompile("`([^`]+)`|([A-Za-z_]\\w*)(\\s*\\()?")
+
+// exprKeywords are the names of a derive expression that are not columns,
+// in Python and in SQL
+var exprKeywords = []string{
+	"and", "or", "not", "in", "is", "if", "else", "True", "False", "None",
+	"AND", "OR", "NOT", "IN", "IS", "NULL", "TRUE", "FALSE", "CASE", "WHEN", "THEN", "ELSE", "END",
+	"LIKE", "BETWEEN", "AS", "BIGINT", "INTEGER", "DOUBLE", "VARCHAR", "DATE", "TIMESTAMP",
+}
+
+// reservedStepNames are names a step function cannot take: Python keywords
+// and the other names of the generated module
+var reservedStepNames = []string{
+	"and", "as", "assert", "async", "await", "break", "class", "continue", "def", "del",
+	"elif", "else", "except", "finally", "for", "from", "global", "if", "import", "in",
+	"is", "lambda", "nonlocal", "not", "or", "pass", "raise", "return", "try", "while",
+	"with", "yield", "None", "True", "False",
+	"logging", "os", "pd", "logger", "Any", "Callable", "List", "STEPS",
+	"to_frame", "to_bool", "read_table", "transform_data",
+	"pl", "duckdb", "F", "Column", "DataFrame", "Window", "numbered", "mode", "to_pandas", "connection", "spark_session",
+}
+
+// withTransformDefaults names the unnamed steps of a spec after their type
+// and position, and fills the defaults of their settings
+func withTransformDefaults(steps []TransformStep) []TransformStep {
+	var named []TransformStep
+	for i, step := range steps {
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("%s_%d", step.Type, i+1)
+		}
+		switch step.Type {
+		case "dedupe":
+			if step.Keep == "" {
+				step.Keep = "first"
+			}
+		case "join":
+			if step.How == "" {
+				step.How = "left"
+			}
+		}
+		named = append(named, step)
+	}
+	return named
+}
+
+// readTransformSpec reads and checks the steps of a YAML or JSON
+// transformation spec file
+func readTransformSpec(path string) ([]TransformStep, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transformation spec: %w", err)
+	}
+
+	var steps []TransformStep
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&steps); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse transformation spec %s: %v", path, err)
+	}
+
+	steps = withTransformDefaults(steps)
+	return steps, validateTransformSpec(steps)
+}
+
+// validateTransformSpec checks the steps of a transformation spec
+func validateTransformSpec(steps []TransformStep) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("transform_spec needs at least one step")
+	}
+
+	names := map[string]bool{}
+	for i, step := range steps {
+		if err := validateTransformStep(step); err != nil {
+			return fmt.Errorf("transform step %d (%s): %w", i+1, step.Type, err)
+		}
+		if names[step.Name] {
+			return fmt.Errorf("transform step %d (%s): name %q is used by another step", i+1, step.Type, step.Name)
+		}
+		names[step.Name] = true
+	}
+	return nil
+}
+
+// validateTransformStep checks the settings a step needs for its type
+func validateTransformStep(step TransformStep) error {
+	if !contains(transformStepTypes, step.Type) {
+		return fmt.Errorf("invalid type. Use one of: %s", strings.Join(transformStepTypes, ", "))
+	}
+	if !namePattern.MatchString(step.Name) {
+		return fmt.Errorf("invalid name %q. Use letters, digits and underscores", step.Name)
+	}
+	if contains(reservedStepNames, step.Name) {
+		return fmt.Errorf("name %q is reserved. Choose another name", step.Name)
+	}
+	for _, text := range stepTexts(step) {
+		if strings.ContainsAny(text, "\r\n\\") || strings.Contains(text, `"""`) {
+			return fmt.Errorf("%q cannot hold line breaks, backslashes or \"\"\", which the generated docstrings cannot quote", text)
+		}
+	}
+
+	switch step.Type {
+	case "rename":
+		if len(step.Columns) == 0 {
+			return fmt.Errorf("columns must map old to new column names")
+		}
+		for column, name := range step.Columns {
+			if name == "" {
+				return fmt.Errorf("column %q has no new name", column)
+			}
+		}
+	case "cast":
+		if len(step.Columns) == 0 {
+			return fmt.Errorf("columns must map column names to types")
+		}
+		for column, typ := range step.Columns {
+			if !contains(castTypes, typ) {
+				return fmt.Errorf("invalid type %q of column %q. Use one of: %s", typ, column, strings.Join(castTypes, ", "))
+			}
+		}
+	case "filter":
+		if step.Column == "" {
+			return fmt.Errorf("column is required")
+		}
+		if !contains(filterOps, step.Op) {
+			return fmt.Errorf("invalid op %q. Use one of: %s", step.Op, strings.Join(filterOps, ", "))
+		}
+		if step.Value == "" && step.Op != "notnull" && step.Op != "isnull" {
+			return fmt.Errorf("value is required for %s", step.Op)
+		}
+	case "dedupe":
+		if step.Keep != "first" && step.Keep != "last" {
+			return fmt.Errorf("invalid keep %q. Use first or last", step.Keep)
+		}
+	case "fill":
+		if len(step.Columns) == 0 && len(step.Values) == 0 {
+			return fmt.Errorf("columns or values must map column names to a strategy or a value")
+		}
+		for column, strategy := range step.Columns {
+			if !contains(fillStrategies, strategy) {
+				return fmt.Errorf("invalid strategy %q of column %q. Use one of: %s, or set a value in values", strategy, column, strings.Join(fillStrategies, ", "))
+			}
+			if _, ok := step.Values[column]; ok {
+				return fmt.Errorf("column %q has both a strategy and a value", column)
+			}
+		}
+	case "derive":
+		if step.Column == "" || step.Expr == "" {
+			return fmt.Errorf("column and expr are required")
+		}
+	case "join":
+		if step.Path == "" {
+			return fmt.Errorf("path of the joined table is required")
+		}
+		if len(step.Keys) == 0 {
+			return fmt.Errorf("keys to join on are required")
+		}
+		if !contains(joinTypes, step.How) {
+			return fmt.Errorf("invalid how %q. Use one of: %s", step.How, strings.Join(joinTypes, ", "))
+		}
+	case "aggregate":
+		if len(step.Keys) == 0 {
+			return fmt.Errorf("keys to group by are required")
+		}
+		if len(step.Metrics) == 0 {
+			return fmt.Errorf("metrics must map output columns to functions, e.g. total: sum(amount)")
+		}
+		for name, metric := range step.Metrics {
+			m, err := parseMetric(name, metric)
+			if err != nil {
+				return err
+			}
+			if contains(step.Keys, m.Column) {
+				return fmt.Errorf("metric %q aggregates the key column %q", name, m.Column)
+			}
+		}
+	}
+	return nil
+}
+
+// stepTexts returns the column names, expressions and paths of a step,
+// which the generated code quotes in docstrings. Fill values are left out
+// as they are rendered by pythonLiteral.
+func stepTexts(step TransformStep) []string {
+	texts := []string{step.Column, step.Value, step.Expr, step.Path}
+	texts = append(texts, step.Keys...)
+	for column, value := range step.Columns {
+		texts = append(texts, column, value)
+	}
+	for column := range step.Values {
+		texts = append(texts, column)
+	}
+	for name, metric := range step.Metrics {
+		texts = append(texts, name, metric)
+	}
+	return texts
+}
+
+// Metric is an output column of an aggregate step
+type Metric struct {
+	Name   string
+	Column string
+	Func   string
+}
+
+// metricFirstGroup is the value of each function over the first of the
+// groups [1.0, 2.0] and [4.0] a test aggregates
+var metricFirstGroup = map[string]string{
+	"sum": "3.0", "mean": "1.5", "median": "1.5", "min": "1.0", "max": "2.0",
+	"count": "2", "nunique": "2", "first": "1.0", "last": "2.0",
+}
+
+// Expected returns the value a test expects of a metric for its first group
+func (m Metric) Expected() string {
+	return metricFirstGroup[m.Func]
+}
+
+// parseMetric parses a metric of an aggregate step, e.g. sum(amount)
+func parseMetric(name, metric string) (Metric, error) {
+	match := metricPattern.FindStringSubmatch(metric)
+	if match == nil || !contains(aggregateFuncs, match[1]) {
+		return Metric{}, fmt.Errorf("invalid metric %q of %q. Use func(column) with one of: %s", metric, name, strings.Join(aggregateFuncs, ", "))
+	}
+	return Metric{Name: name, Column: match[2], Func: match[1]}, nil
+}
+
+// AggregateMetrics returns the metrics of an aggregate step ordered by name
+func (s TransformStep) AggregateMetrics() []Metric {
+	var metrics []Metric
+	for _, name := range sortedKeys(s.Metrics) {
+		metric, _ := parseMetric(name, s.Metrics[name])
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+// AggregateColumns returns the columns the metrics of an aggregate step read,
+// in order of the metrics
+func (s TransformStep) AggregateColumns() []string {
+	var columns []string
+	for _, metric := range s.AggregateMetrics() {
+		if !contains(columns, metric.Column) {
+			columns = append(columns, metric.Column)
+		}
+	}
+	return columns
+}
+
+// FillValues returns the literal values of a fill step as Python literals,
+// keyed by column
+func (s TransformStep) FillValues() map[string]string {
+	values := map[string]string{}
+	for column, value := range s.Values {
+		values[column] = pythonLiteral(value)
+	}
+	return values
+}
+
+// FilterValue returns the compared value of a filter step as a Python
+// literal, a list of them for in and not in
+func (s TransformStep) FilterValue() string {
+	if s.Op == "in" || s.Op == "not in" {
+		var items []string
+		for _, item := range strings.Split(s.Value, ",") {
+			items = append(items, pythonLiteral(strings.TrimSpace(item)))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return pythonLiteral(s.Value)
+}
+
+// PathVariable returns the environment variable overriding the path of the
+// table a join step reads
+func (s TransformStep) PathVariable() string {
+	return strings.ToUpper(s.Name) + "_PATH"
+}
+
+// FillCase is a column a test fills: Input holds three values, the second
+// missing and replaced by Expected
+type FillCase struct {
+	Column   string
+	Input    string
+	Expected string
+}
+
+// fillExpected is the value each strategy fills in [1.0, None, 3.0]
+var fillExpected = map[string]string{
+	"mean": "2.0", "median": "2.0", "mode": "1.0", "zero": "0", "ffill": "1.0", "bfill": "3.0",
+}
+
+// FillCases returns the columns of a fill step with the values a test
+// fills and expects, ordered by column
+func (s TransformStep) FillCases() []FillCase {
+	var cases []FillCase
+	for _, column := range sortedKeys(s.Columns) {
+		cases = append(cases, FillCase{Column: column, Input: "[1.0, None, 3.0]", Expected: fillExpected[s.Columns[column]]})
+	}
+	for _, column := range sortedKeys(s.Values) {
+		value := pythonLiteral(s.Values[column])
+		cases = append(cases, FillCase{Column: column, Input: "[" + value + ", None, " + value + "]", Expected: value})
+	}
+	sort.Slice(cases, func(i, j int) bool { return cases[i].Column < cases[j].Column })
+	return cases
+}
+
+// FilterCase is the column a test filters and the number of its values the
+// filter keeps
+type FilterCase struct {
+	Input string
+	Kept  int
+}
+
+// FilterCase returns values of the column of a filter step around its
+// compared value, and how many of them it keeps
+func (s TransformStep) FilterCase() FilterCase {
+	switch s.Op {
+	case "notnull", "isnull":
+		return FilterCase{Input: "[1, None]", Kept: 1}
+	case "in", "not in":
+		items := strings.Split(s.Value, ",")
+		input := strings.TrimSuffix(s.FilterValue(), "]") + `, "__other__"]`
+		if s.Op == "in" {
+			return FilterCase{Input: input, Kept: len(items)}
+		}
+		return FilterCase{Input: input, Kept: 1}
+	}
+
+	// Values just below, at and above a number; the value and another string
+	// otherwise
+	var samples []string
+	var compare func(i int) int
+	if value, err := strconv.ParseFloat(s.Value, 64); err == nil && pythonLiteral(s.Value) == s.Value {
+		samples = []string{formatNumber(value - 1), formatNumber(value), formatNumber(value + 1)}
+		compare = func(i int) int { return i - 1 }
+	} else {
+		other := s.Value + "_other"
+		samples = []string{pythonLiteral(s.Value), strconv.Quote(other)}
+		compare = func(i int) int { return strings.Compare([]string{s.Value, other}[i], s.Value) }
+	}
+
+	kept := 0
+	for i := range samples {
+		c := compare(i)
+		switch {
+		case s.Op == "==" && c == 0, s.Op == "!=" && c != 0,
+			s.Op == ">" && c > 0, s.Op == ">=" && c >= 0,
+			s.Op == "<" && c < 0, s.Op == "<=" && c <= 0:
+			kept++
+		}
+	}
+	return FilterCase{Input: "[" + strings.Join(samples, ", ") + "]", Kept: kept}
+}
+
+// TypedFilterCase is FilterCase with values of a single type, for engines
+// whose columns cannot mix them
+func (s TransformStep) TypedFilterCase() FilterCase {
+	c := s.FilterCase()
+	if s.Op != "in" && s.Op != "not in" {
+		return c
+	}
+
+	// A number above every compared one, when they are all numbers
+	var items []string
+	highest := 0.0
+	for i, item := range strings.Split(s.Value, ",") {
+		item = strings.TrimSpace(item)
+		value, err := strconv.ParseFloat(item, 64)
+		if err != nil || pythonLiteral(item) != item {
+			return c
+		}
+		if i == 0 || value > highest {
+			highest = value
+		}
+		items = append(items, item)
+	}
+	c.Input = "[" + strings.Join(items, ", ") + ", " + formatNumber(highest+1) + "]"
+	return c
+}
+
+// formatNumber renders a number the way it is written in a spec
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// ExprColumns returns the columns a derive expression reads, in order of
+// appearance. Names called as functions and keywords are left out.
+func (s TransformStep) ExprColumns() []string {
+	expr := exprStringPattern.ReplaceAllString(s.Expr, `""`)
+
+	var columns []string
+	for _, match := range exprNamePattern.FindAllStringSubmatchIndex(expr, -1) {
+		name := ""
+		switch {
+		case match[2] >= 0:
+			name = expr[match[2]:match[3]]
+		case match[4] > 0 && strings.ContainsAny(expr[match[4]-1:match[4]], "._0123456789"):
+			// An attribute, such as str.len, or the exponent of a number
+			continue
+		case match[6] >= 0 || contains(exprKeywords, expr[match[4]:match[5]]):
+			continue
+		default:
+			name = expr[match[4]:match[5]]
+		}
+		if !contains(columns, name) {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+// pythonLiteral renders a spec value as a Python number, boolean or string
+func pythonLiteral(value string) string {
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return value
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil && !strings.ContainsAny(value, "xXnN") {
+		return value
+	}
+	switch strings.ToLower(value) {
+	case "true":
+		return "True"
+	case "false":
+		return "False"
+	}
+	return strconv.Quote(value)
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,46 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTransformStepRejectsDocstringBreaks(t *testing.T) {
+	tests := []struct {
+		name string
+		step TransformStep
+	}{
+		{name: "derive expr with quotes", step: TransformStep{Type: "derive", Column: "total", Expr: `price * 2 """`}},
+		{name: "derive column with a newline", step: TransformStep{Type: "derive", Column: "total\nimport os", Expr: "price * 2"}},
+		{name: "filter value with a backslash", step: TransformStep{Type: "filter", Column: "name", Op: "==", Value: `\N`}},
+		{name: "rename column with a carriage return", step: TransformStep{Type: "rename", Columns: map[string]string{"a\r": "b"}}},
+		{name: "dedupe key with quotes", step: TransformStep{Type: "dedupe", Keep: "first", Keys: []string{`id"""`}}},
+		{name: "join path with a newline", step: TransformStep{Type: "join", How: "left", Keys: []string{"id"}, Path: "a.csv\n"}},
+		{name: "fill column with quotes", step: TransformStep{Type: "fill", Values: map[string]string{`a"""`: "0"}}},
+		{name: "aggregate metric with a newline", step: TransformStep{Type: "aggregate", Keys: []string{"id"}, Metrics: map[string]string{"total\n": "sum(amount)"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.step.Name = "step"
+			err := validateTransformStep(tt.step)
+			if err == nil || !strings.Contains(err.Error(), "cannot hold") {
+				t.Errorf("validateTransformStep() error = %v, want a rejected text", err)
+			}
+		})
+	}
+}
+
+func TestValidateTransformStepAcceptsQuotedText(t *testing.T) {
+	steps := []TransformStep{
+		{Type: "derive", Column: "label", Expr: `name + " (" + code + ")"`},
+		{Type: "filter", Column: "name", Op: "==", Value: `O'Brien "Jr"`},
+		{Type: "fill", Values: map[string]string{"path": `C:\data`}},
+	}
+	for _, step := range steps {
+		step.Name = "step"
+		if err := validateTransformStep(step); err != nil {
+			t.Errorf("validateTransformStep(%+v) error = %v", step, err)
+		}
+	}
+}
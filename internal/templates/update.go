@@ -0,0 +1,219 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// fileUpdate is the outcome of merging one project file
+type fileUpdate struct {
+	Path    string
+	Action  string // added, updated, merged, conflict or removed
+	Content []byte
+	Base    []byte // template content recorded as the new merge base
+	NoBase  bool   // no merge base is recorded, as for a conflicting untracked file
+	Change  string // template change that could not be merged
+}
+
+// UpdateProject returns the action of the update command, which re-renders
+// a generated project with the current version of its template and merges
+// the result with the edits made since
+func UpdateProject(reg *Registry) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		dir := c.String("dir")
+
+		lock, err := readLock(dir)
+		if err != nil {
+			return err
+		}
+
+		t, ok := reg.Lookup(lock.Template)
+		if !ok {
+			return fmt.Errorf("template %s used by %s is not installed", lock.Template, dir)
+		}
+
+		p, err := renderFromLock(t, lock)
+		if err != nil {
+			return err
+		}
+
+		updates, err := mergeProject(dir, lock, p.Files)
+		if err != nil {
+			return err
+		}
+
+		if lock.Version != t.Version {
+			fmt.Printf("Updating %s from version %s to %s\n", t.Name, lock.Version, t.Version)
+		}
+		return applyUpdates(dir, t, lock, updates, c.Bool("dry-run"))
+	}
+}
+
+// mergeProject three-way merges each file of the re-rendered project with
+// the file on disk, using the content recorded in the lock as the base
+func mergeProject(dir string, lock *Lock, files []RenderedFile) ([]fileUpdate, error) {
+	var updates []fileUpdate
+	rendered := make(map[string]bool, len(files))
+
+	for _, file := range files {
+		rendered[file.Path] = true
+		theirs := string(file.Content)
+
+		ours, exists, err := readProjectFile(dir, file.Path)
+		if err != nil {
+			return nil, err
+		}
+		base, tracked := lock.Files[file.Path]
+
+		u := fileUpdate{Path: file.Path, Base: file.Content}
+		switch {
+		case !tracked && !exists:
+			u.Action, u.Content = "added", file.Content
+		case !tracked:
+			if ours == theirs {
+				u.Action = "unchanged"
+			} else {
+				u.Action = "conflict"
+				u.Change = unifiedDiff(file.Path, file.Path, []byte(ours), file.Content)
+			}
+		case theirs == base:
+			// The template did not change this file
+			u.Action = "unchanged"
+		case !exists:
+			u.Action = "conflict"
+			u.Change = unifiedDiff(file.Path, file.Path, []byte(base), file.Content)
+		case ours == base:
+			u.Action, u.Content = "updated", file.Content
+		case ours == theirs:
+			u.Action = "unchanged"
+		default:
+			merged, ok := mergeFiles(base, ours, theirs)
+			if ok {
+				u.Action, u.Content = "merged", []byte(merged)
+			} else {
+				u.Action = "conflict"
+				u.Change = unifiedDiff(file.Path, file.Path, []byte(base), file.Content)
+			}
+		}
+
+		if u.Action == "conflict" {
+			// Keep the old base, or none, so the next update sees the change
+			// again until the user resolves it
+			u.Base, u.NoBase = []byte(base), !tracked
+		}
+		updates = append(updates, u)
+	}
+
+	// Files the template no longer generates are left in place
+	var removed []string
+	for path := range lock.Files {
+		if !rendered[path] {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(removed)
+	for _, path := range removed {
+		updates = append(updates, fileUpdate{Path: path, Action: "removed"})
+	}
+
+	return updates, nil
+}
+
+// readProjectFile reads a project file, reporting whether it exists
+func readProjectFile(dir, path string) (string, bool, error) {
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return string(content), true, nil
+}
+
+// applyUpdates reports the merge results and writes the changed files and
+// the new lock file, leaving conflicting files untouched
+func applyUpdates(dir string, t *Template, lock *Lock, updates []fileUpdate, dryRun bool) error {
+	next := Lock{
+		Template: t.Name,
+		Version:  t.Version,
+		Answers:  lock.Answers,
+		Files:    make(map[string]string),
+	}
+
+	var files []RenderedFile
+	var conflicts []string
+	for _, u := range updates {
+		switch u.Action {
+		case "unchanged":
+		case "removed":
+			fmt.Printf("  removed   %s (no longer part of the template, left in place)\n", u.Path)
+		case "conflict":
+			fmt.Printf("  conflict  %s\n", u.Path)
+			conflicts = append(conflicts, u.Path)
+		default:
+			fmt.Printf("  %-9s %s\n", u.Action, u.Path)
+			files = append(files, RenderedFile{Path: u.Path, Content: u.Content})
+		}
+		if u.Action != "removed" && !u.NoBase {
+			next.Files[u.Path] = string(u.Base)
+		}
+	}
+
+	for _, u := range updates {
+		if u.Action == "conflict" && u.Change != "" {
+			fmt.Printf("\nTemplate changes to %s that could not be merged with your edits:\n%s", u.Path, u.Change)
+		}
+	}
+
+	if len(files) == 0 && len(conflicts) == 0 && lock.Version == t.Version && sameFiles(lock.Files, next.Files) {
+		fmt.Println("Project is already up to date")
+		return nil
+	}
+	if dryRun {
+		fmt.Println("\nDry run: no files were written")
+		return nil
+	}
+
+	lockFile, err := encodeLock(next)
+	if err != nil {
+		return err
+	}
+	files = append(files, lockFile)
+
+	staged, err := stageProject(dir, files)
+	if err != nil {
+		return err
+	}
+	defer staged.cleanup()
+
+	if err := staged.commit(files); err != nil {
+		return err
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%d file(s) have conflicts and were left untouched: %s; apply the template changes shown above by hand",
+			len(conflicts), strings.Join(conflicts, ", "))
+	}
+
+	fmt.Printf("Project in %s updated to %s %s\n", dir, t.Name, t.Version)
+	return nil
+}
+
+// sameFiles reports whether two lock file contents are equal
+func sameFiles(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, content := range a {
+		if other, ok := b[path]; !ok || other != content {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteProjectKeepsTheBaseOfSkippedFiles(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := &Template{Manifest: Manifest{Name: "test", Version: "1"}}
+	p := renderedProject{
+		Template: tmpl,
+		Dir:      dir,
+		Files: []RenderedFile{
+			{Path: "a.txt", Content: []byte("a1\n")},
+			{Path: "b.txt", Content: []byte("b1\n")},
+			{Path: "c.txt", Content: []byte("c1\n")},
+		},
+	}
+	if err := writeProject(p, GenerateOptions{OnConflict: ConflictFail}); err != nil {
+		t.Fatal(err)
+	}
+
+	// b.txt is edited by hand, and c.txt becomes an untracked file of the user
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := readLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(lock.Files, "c.txt")
+	lockFile, err := encodeLock(*lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFile(filepath.Join(dir, LockFile), lockFile.Content); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "c.txt"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p.Files = []RenderedFile{
+		{Path: "a.txt", Content: []byte("a1\n")},
+		{Path: "b.txt", Content: []byte("b2\n")},
+		{Path: "c.txt", Content: []byte("c2\n")},
+		{Path: "d.txt", Content: []byte("d2\n")},
+	}
+	if err := writeProject(p, GenerateOptions{OnConflict: ConflictSkip}); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err = readLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lock.Files["d.txt"]; got != "d2\n" {
+		t.Errorf("base of the new d.txt = %q, want its content", got)
+	}
+	if got := lock.Files["b.txt"]; got != "b1\n" {
+		t.Errorf("base of the skipped b.txt = %q, want the previous base", got)
+	}
+	if got, ok := lock.Files["c.txt"]; ok {
+		t.Errorf("skipped untracked c.txt was recorded with base %q", got)
+	}
+}
+
+func TestMergeProjectKeepsNoBaseForUntrackedConflicts(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeFile(filepath.Join(dir, "a.txt"), []byte("mine\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFile(filepath.Join(dir, "b.txt"), []byte("ours\n")); err != nil {
+		t.Fatal(err)
+	}
+	lock := &Lock{Files: map[string]string{"b.txt": "base\n"}}
+	files := []RenderedFile{
+		{Path: "a.txt", Content: []byte("theirs\n")},
+		{Path: "b.txt", Content: []byte("theirs\n")},
+	}
+
+	updates, err := mergeProject(dir, lock, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range updates {
+		if u.Action != "conflict" {
+			t.Fatalf("%s: action = %s, want a conflict", u.Path, u.Action)
+		}
+	}
+	if !updates[0].NoBase {
+		t.Errorf("untracked a.txt records a base of %q", updates[0].Base)
+	}
+	if updates[1].NoBase || string(updates[1].Base) != "base\n" {
+		t.Errorf("tracked b.txt base = %q, want the old base", updates[1].Base)
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
     "os"
+    "path/filepath"
     "runtime"
 )
 
@@ -12,14 +13,25 @@ func GetTemplatesDir() string {
         return "templates"
     }
     
-    // For installed application, determine templates directory based on OS
+    // For installed application, use the templates directory in the config dir
+    return filepath.Join(GetConfigDir(), "templates")
+}
+
+// GetConfigDir returns the per-user pytgen configuration directory
+func GetConfigDir() string {
+    // Determine configuration directory based on OS
     switch runtime.GOOS {
     case "windows":
         // Windows typically uses %APPDATA%
-        return filepath.Join(os.Getenv("APPDATA"), "pytgen", "templates")
+        return filepath.Join(os.Getenv("APPDATA"), "pytgen")
     default:
         // Linux/macOS typically uses ~/.config
         homeDir, _ := os.UserHomeDir()
-        return filepath.Join(homeDir, ".config", "pytgen", "templates")
+        return filepath.Join(homeDir, ".config", "pytgen")
     }
-}
\ No newline at end of file
+}
+
+// GetCacheDir returns the directory holding templates fetched from remote sources
+func GetCacheDir() string {
+    return filepath.Join(GetConfigDir(), "cache")
+}
@@ -0,0 +1,12 @@
+// Package templates embeds the built-in project templates so pytgen works
+// regardless of the directory it is run from.
+package templates
+
+import "embed"
+
+// FS holds the built-in template tree. Every directory containing a
+// template.yaml manifest is a template. The "all:" prefix is required so that
+// dotfiles such as .gitignore.tmpl are embedded too.
+//
+//go:embed all:*
+var FS embed.FS